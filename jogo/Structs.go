@@ -1,28 +1,259 @@
 package main
 
+import (
+    "encoding/json"
+    "fmt"
+)
+
 // Comando define a requisição enviada do Cliente para o Servidor.
+// Cada ação leva apenas os seus dados (tabela acoesComando, server_comandos.go);
+// os demais campos Dados* ficam nil.
 type Comando struct {
     ClientID        string 
     SequenceNumber  int   
     Acao            string 
-    Detalhe         string 
+    Versao          int    // Versão do protocolo usada pelo cliente (VersaoProtocolo, protocolo.go)
+    Token           string // Token da sessão recebido no registro: exigido em todo comando do jogador e na retomada
+    UltimoTick      uint64 // Último tick recebido pelo cliente (0 pede um snapshot completo)
+
+    Registro  *DadosRegistro  // "register"
+    Movimento *DadosMovimento // "move"
+    Interacao *DadosInteracao // "interact"
+    Chat      *DadosChat      // "chat"
+    Sala      *DadosSala      // "create" e "join"
+}
+
+// DadosRegistro traz o nome pedido pelo jogador (vazio: o servidor escolhe um),
+// os recursos do protocolo que o cliente sabe usar (Recurso*, protocolo.go) e um
+// nonce aleatório gerado pelo cliente (de 32 a 64 bytes, como 16 bytes aleatórios em
+// hexadecimal), que identifica as retransmissões do registro (antes dele o cliente
+// ainda não tem ClientID).
+type DadosRegistro struct {
+    Nome     string
+    Recursos []string
+    Nonce    string
+}
+
+// DadosMovimento traz a direção do movimento: "w", "a", "s" ou "d".
+type DadosMovimento struct {
+    Direcao string
+}
+
+// DadosInteracao traz a célula com que o jogador quer interagir:
+// a sua própria ou uma vizinha.
+type DadosInteracao struct {
+    X, Y int
+}
+
+// DadosChat traz uma mensagem para os jogadores da sala.
+type DadosChat struct {
+    Texto string
+}
+
+// DadosSala identifica a sala de "join" e "create"; Mapa só é usado em "create".
+type DadosSala struct {
+    Nome string
+    Mapa string
 }
 
 // EstadoJogador representa a posição e vida de um jogador.
 type EstadoJogador struct {
+    Nome          string // Nome escolhido pelo jogador, único no servidor
     X, Y          int
     Vidas         int
     UltimoComando int 
+    Pronto        bool // Marcou-se pronto na sala de espera
+}
+
+// Fases de uma sala: espera (lobby) antes da rodada e rodada em andamento.
+const (
+    FaseEspera = "espera"
+    FaseJogo   = "jogo"
+)
+
+// Tipos de entidades autônomas simuladas pelo servidor.
+const (
+    TipoGuarda    = "guarda"
+    TipoPortal    = "portal"
+    TipoArmadilha = "armadilha"
+)
+
+// EstadoEntidade representa a posição de um elemento autônomo (guarda, portal, armadilha).
+type EstadoEntidade struct {
+    Tipo string
+    X, Y int
 }
 
-// EstadoJogo representa o estado completo que o servidor mantém.
+// EstadoJogo representa o estado completo de uma sala do servidor.
+// Os jogadores são identificados pelo identificador público, nunca pelo ClientID.
 type EstadoJogo struct {
+    Sala      string   // Sala a que o estado pertence
+    Fase      string   // FaseEspera ou FaseJogo
+    Contagem  int      // Segundos até a rodada começar (na espera, após o primeiro jogador ficar pronto)
+    Tick      uint64   // Tick da simulação em que o estado foi produzido
     Jogadores map[string]EstadoJogador
+    Entidades map[string]EstadoEntidade
+    Aviso     string   // Última mensagem anunciada a todos (entrada e saída de jogadores)
+    AvisoTick uint64   // Tick em que o aviso foi anunciado
+    Mapa      []string // Mapa gerado para a rodada (salas com -gerar), que substitui o da sala
+    MapaTick  uint64   // Tick em que o mapa foi gerado (0: mapa do arquivo)
+}
+
+// DeltaEstado descreve o que mudou no estado entre o tick confirmado pelo cliente
+// (TickBase) e o tick atual. Se Completo for true, é um snapshot que substitui
+// todo o estado local e TickBase deve ser ignorado.
+type DeltaEstado struct {
+    Sala               string // Sala do estado; deltas de outra sala são descartados
+    Fase               string // Fase e contagem da sala, sempre enviadas
+    Contagem           int
+    FaseMudou          bool   // Fase ou contagem mudaram desde TickBase
+    TickBase           uint64
+    Tick               uint64
+    Completo           bool
+    Jogadores          map[string]EstadoJogador  // Jogadores que entraram ou mudaram
+    JogadoresRemovidos []string                  // Jogadores que saíram
+    Entidades          map[string]EstadoEntidade // Entidades que surgiram ou mudaram
+    EntidadesRemovidas []string                  // Entidades que deixaram de existir
+    Aviso              string                    // Novo aviso, se AvisoTick for diferente de zero
+    AvisoTick          uint64
+    Mapa               []string                  // Novo mapa gerado, se MapaTick mudou desde TickBase
+    MapaTick           uint64                    // Tick em que o mapa atual da sala foi gerado (sempre enviado)
 }
 
 // Resposta define a resposta retornada do Servidor para o Cliente.
+// O cliente decide o que fazer pelo Codigo, pelo Motivo e pelos Eventos;
+// a Mensagem é apenas um texto opcional para exibir ao jogador.
 type Resposta struct {
-    Sucesso     bool
-    Mensagem    string
-    EstadoAtual EstadoJogo 
+    Codigo      CodigoResposta
+    Motivo      string      // Motivo da recusa, legível por máquina (constantes Motivo*)
+    Mensagem    string      // Texto opcional para o jogador
+    Eventos     []Evento    // Acontecimentos da sala no tick que processou o comando
+    Delta       DeltaEstado // Mudanças desde Comando.UltimoTick
+    ClientID    string      // Identificador da sessão atribuído pelo servidor (apenas em "register")
+    Token       string      // Token da sessão, enviado em todo comando (apenas em "register")
+    JogadorID   string      // Identificador público do jogador no estado e nos eventos (apenas em "register")
+    Sala        string      // Sala em que o jogador acabou de entrar ("register", "join", "create")
+    Mapa        []string    // Linhas do mapa dessa sala, no formato de mapa.go (cabeçalho, legenda e desenho)
+    Salas       []InfoSala  // Salas existentes (apenas em "rooms")
+    Recursos    []string    // Recursos acordados com o servidor (apenas em "register")
+}
+
+// Ok indica se o comando foi aplicado.
+func (r Resposta) Ok() bool {
+    return r.Codigo == CodigoOK
+}
+
+// CodigoResposta classifica o resultado de um comando.
+type CodigoResposta int
+
+const (
+    CodigoOK            CodigoResposta = iota // Comando aplicado
+    CodigoRecusado                            // Comando válido, mas impedido pelas regras do jogo
+    CodigoInvalido                            // Comando mal formado ou ação desconhecida
+    CodigoNaoRegistrado                       // ClientID sem sessão ativa no servidor
+    CodigoConflito                            // Nome de jogador ou de sala já em uso
+    CodigoNaoEncontrado                       // Sala ou mapa inexistente
+    CodigoExpirado                            // Comando antigo, fora do cache de respostas
+    CodigoIncompativel                        // Versão de protocolo do cliente não aceita
+)
+
+func (c CodigoResposta) String() string {
+    switch c {
+    case CodigoOK:
+        return "ok"
+    case CodigoRecusado:
+        return "recusado"
+    case CodigoInvalido:
+        return "invalido"
+    case CodigoNaoRegistrado:
+        return "nao_registrado"
+    case CodigoConflito:
+        return "conflito"
+    case CodigoNaoEncontrado:
+        return "nao_encontrado"
+    case CodigoExpirado:
+        return "expirado"
+    case CodigoIncompativel:
+        return "incompativel"
+    }
+    return "desconhecido"
+}
+
+// MarshalJSON envia o código pelo nome ("ok", "recusado", ...) no gateway JSON.
+func (c CodigoResposta) MarshalJSON() ([]byte, error) {
+    return json.Marshal(c.String())
+}
+
+// UnmarshalJSON aceita o código pelo nome, como enviado por MarshalJSON.
+func (c *CodigoResposta) UnmarshalJSON(dados []byte) error {
+    var nome string
+    if err := json.Unmarshal(dados, &nome); err != nil {
+        return err
+    }
+    for codigo := CodigoOK; codigo <= CodigoIncompativel; codigo++ {
+        if codigo.String() == nome {
+            *c = codigo
+            return nil
+        }
+    }
+    return fmt.Errorf("código de resposta desconhecido: %q", nome)
+}
+
+// Motivos de recusa enviados em Resposta.Motivo.
+const (
+    MotivoForaDoMapa          = "fora_do_mapa"
+    MotivoParede              = "parede"
+    MotivoInimigo             = "inimigo"
+    MotivoGuarda              = "guarda"
+    MotivoCelulaOcupada       = "celula_ocupada"
+    MotivoSemCelulaLivre      = "sem_celula_livre"
+    MotivoMovimentoSimultaneo = "movimento_simultaneo"
+    MotivoFimDeJogo           = "fim_de_jogo"
+    MotivoRodadaNaoIniciada   = "rodada_nao_iniciada"
+    MotivoRodadaIniciada      = "rodada_iniciada"
+    MotivoDirecaoInvalida     = "direcao_invalida"
+    MotivoAcaoDesconhecida    = "acao_desconhecida"
+    MotivoFormatoInvalido     = "formato_invalido"
+    MotivoProtocoloIncompativel = "protocolo_incompativel"
+    MotivoRecursoNaoAcordado    = "recurso_nao_acordado"
+    MotivoAlvoInvalido        = "alvo_invalido"
+    MotivoTextoInvalido       = "texto_invalido"
+    MotivoNomeInvalido        = "nome_invalido"
+    MotivoNomeEmUso           = "nome_em_uso"
+    MotivoNonceEmUso          = "nonce_em_uso"
+    MotivoSalaInvalida        = "sala_invalida"
+    MotivoSalaExistente       = "sala_existente"
+    MotivoSalaNaoEncontrada   = "sala_nao_encontrada"
+    MotivoMapaInvalido        = "mapa_invalido"
+    MotivoNaoRegistrado       = "nao_registrado"
+    MotivoTokenInvalido       = "token_invalido"
+    MotivoForaDoCache         = "fora_do_cache"
+)
+
+// Tipos de Evento.
+const (
+    EventoJogadorEntrou  = "jogador_entrou"
+    EventoJogadorSaiu    = "jogador_saiu"
+    EventoArmadilha      = "armadilha"
+    EventoPortal         = "portal"
+    EventoInimigo        = "inimigo"
+    EventoFimDeJogo      = "fim_de_jogo"
+    EventoRodadaIniciada = "rodada_iniciada"
+    EventoChat           = "chat"
+)
+
+// Evento é um acontecimento do jogo numa sala, como cair numa armadilha ou
+// um jogador entrar. X e Y indicam onde ele ocorreu, quando se aplica.
+type Evento struct {
+    Tipo    string
+    Jogador string // Identificador público do jogador envolvido, se houver
+    X, Y    int
+    Texto   string // Mensagem de EventoChat
+}
+
+// InfoSala resume uma sala para a listagem de salas.
+type InfoSala struct {
+    Nome      string
+    Mapa      string // Arquivo do mapa usado pela sala
+    Jogadores int
 }
\ No newline at end of file
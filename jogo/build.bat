@@ -11,6 +11,7 @@ IF NOT EXIST go.mod (
 REM Compila o projeto
 echo Compilando...
 go build -o jogo.exe
+go build -tags servidor -o server_jogo.exe
 
 REM Fim
 exit /b
@@ -0,0 +1,105 @@
+// camadas.go - Mapa em camadas: terreno, itens, entidades e sobreposições
+// O terreno vem do arquivo do mapa e não muda durante a partida. Tudo o que fica
+// sobre ele mora numa camada própria, como objetos identificados por ID com a sua
+// posição: itens parados no chão (portais e armadilhas), entidades que andam
+// (jogadores e guardas) e sobreposições da interface (o cursor do editor). Nada é
+// pintado no terreno; as camadas só são combinadas no desenho, com a mais alta por
+// cima (interfaceDesenharCamadas).
+package main
+
+// Camadas acima do terreno, da mais baixa para a mais alta
+const (
+	CamadaItens = iota
+	CamadaEntidades
+	CamadaSobreposicao
+	numCamadas
+)
+
+// camadaEntidade é a camada de cada tipo de entidade autônoma do servidor: portais e
+// armadilhas ficam no chão, como itens; o guarda anda, como os jogadores.
+var camadaEntidade = map[string]int{
+	TipoGuarda:    CamadaEntidades,
+	TipoPortal:    CamadaItens,
+	TipoArmadilha: CamadaItens,
+}
+
+// ObjetoMapa é um objeto posicionado numa camada do mapa.
+type ObjetoMapa struct {
+	Elemento
+	X, Y int
+}
+
+// MapaCamadas é o terreno do mapa e os objetos de cada camada acima dele, por ID.
+type MapaCamadas struct {
+	Terreno [][]Elemento
+	camadas [numCamadas]map[string]ObjetoMapa
+}
+
+// definir põe (ou move) o objeto id da camada na posição (x, y). Posições fora do
+// terreno são aceitas e o objeto apenas não aparece (ex.: jogadores na espera).
+func (m *MapaCamadas) definir(camada int, id string, elem Elemento, x, y int) {
+	if m.camadas[camada] == nil {
+		m.camadas[camada] = make(map[string]ObjetoMapa)
+	}
+	m.camadas[camada][id] = ObjetoMapa{Elemento: elem, X: x, Y: y}
+}
+
+// remover tira o objeto id da camada, se houver.
+func (m *MapaCamadas) remover(camada int, id string) {
+	delete(m.camadas[camada], id)
+}
+
+// limpar tira todos os objetos da camada.
+func (m *MapaCamadas) limpar(camada int) {
+	m.camadas[camada] = nil
+}
+
+// objeto retorna o objeto id da camada.
+func (m *MapaCamadas) objeto(camada int, id string) (ObjetoMapa, bool) {
+	o, ok := m.camadas[camada][id]
+	return o, ok
+}
+
+// dentro indica se (x, y) é uma célula do terreno.
+func (m *MapaCamadas) dentro(x, y int) bool {
+	return y >= 0 && y < len(m.Terreno) && x >= 0 && x < len(m.Terreno[y])
+}
+
+// largura retorna a largura da linha mais longa do terreno.
+func (m *MapaCamadas) largura() int {
+	largura := 0
+	for _, linha := range m.Terreno {
+		largura = max(largura, len(linha))
+	}
+	return largura
+}
+
+// compor retorna, para cada célula do terreno com algum objeto, o elemento visível:
+// o da camada mais alta e, na mesma camada, o do maior ID.
+func (m *MapaCamadas) compor() map[Posicao]Elemento {
+	visiveis := make(map[Posicao]Elemento)
+	for _, camada := range m.camadas {
+		for _, id := range idsOrdenados(camada) {
+			if o := camada[id]; m.dentro(o.X, o.Y) {
+				visiveis[Posicao{o.X, o.Y}] = o.Elemento
+			}
+		}
+	}
+	return visiveis
+}
+
+// elementoEm retorna o elemento visível na célula (x, y), como em compor.
+func (m *MapaCamadas) elementoEm(x, y int) Elemento {
+	for c := numCamadas - 1; c >= 0; c-- {
+		ids := idsOrdenados(m.camadas[c])
+		for i := len(ids) - 1; i >= 0; i-- {
+			if o := m.camadas[c][ids[i]]; o.X == x && o.Y == y {
+				return o.Elemento
+			}
+		}
+	}
+	if m.dentro(x, y) {
+		return m.Terreno[y][x]
+	}
+	return Vazio
+}
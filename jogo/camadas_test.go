@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// mapaCamadasTeste cria um terreno 4x3 com vegetação em (1, 1) e parede em (3, 2).
+func mapaCamadasTeste() MapaCamadas {
+	return MapaCamadas{Terreno: [][]Elemento{
+		{Vazio, Vazio, Vazio, Vazio},
+		{Vazio, Vegetacao, Vazio, Vazio},
+		{Vazio, Vazio, Vazio, Parede},
+	}}
+}
+
+func TestCamadasCompor(t *testing.T) {
+	type objeto struct {
+		camada int
+		id     string
+		elem   Elemento
+		x, y   int
+	}
+	casos := []struct {
+		nome     string
+		objetos  []objeto
+		visiveis map[Posicao]Elemento
+	}{
+		{
+			nome:     "sem objetos só o terreno aparece",
+			visiveis: map[Posicao]Elemento{},
+		},
+		{
+			nome: "a camada mais alta fica por cima",
+			objetos: []objeto{
+				{CamadaEntidades, "guarda", ElementoGuarda, 1, 1},
+				{CamadaItens, "portal", ElementoPortal, 1, 1},
+				{CamadaSobreposicao, "cursor", Parede, 2, 0},
+				{CamadaItens, "armadilha", ElementoArmadilha, 2, 0},
+			},
+			visiveis: map[Posicao]Elemento{{1, 1}: ElementoGuarda, {2, 0}: Parede},
+		},
+		{
+			nome: "na mesma camada vence o maior ID",
+			objetos: []objeto{
+				{CamadaEntidades, "p-b", Personagem, 0, 2},
+				{CamadaEntidades, "p-a", ElementoGuarda, 0, 2},
+				{CamadaEntidades, "p-c", Inimigo, 3, 0},
+			},
+			visiveis: map[Posicao]Elemento{{0, 2}: Personagem, {3, 0}: Inimigo},
+		},
+		{
+			nome: "objetos fora do terreno não aparecem",
+			objetos: []objeto{
+				{CamadaEntidades, "espera", Personagem, -1, -1},
+				{CamadaItens, "longe", ElementoPortal, 4, 1},
+			},
+			visiveis: map[Posicao]Elemento{},
+		},
+		{
+			nome: "definir de novo move o objeto",
+			objetos: []objeto{
+				{CamadaEntidades, "p-a", Personagem, 0, 0},
+				{CamadaEntidades, "p-a", Personagem, 2, 1},
+			},
+			visiveis: map[Posicao]Elemento{{2, 1}: Personagem},
+		},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			m := mapaCamadasTeste()
+			for _, o := range caso.objetos {
+				m.definir(o.camada, o.id, o.elem, o.x, o.y)
+			}
+			visiveis := m.compor()
+			if !reflect.DeepEqual(visiveis, caso.visiveis) {
+				t.Errorf("compor = %v, esperado %v", visiveis, caso.visiveis)
+			}
+			// elementoEm concorda com compor e, sem objeto, mostra o terreno
+			for y, linha := range m.Terreno {
+				for x, terreno := range linha {
+					esperado, ok := visiveis[Posicao{x, y}]
+					if !ok {
+						esperado = terreno
+					}
+					if e := m.elementoEm(x, y); e != esperado {
+						t.Errorf("elementoEm(%d, %d) = %q, esperado %q", x, y, e.simbolo, esperado.simbolo)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCamadasRemoverPreservaTerreno(t *testing.T) {
+	m := mapaCamadasTeste()
+	antes := mapaCamadasTeste().Terreno
+	m.definir(CamadaItens, "portal", ElementoPortal, 1, 1)
+	m.definir(CamadaEntidades, "guarda", ElementoGuarda, 1, 1)
+	m.definir(CamadaEntidades, "p-a", Personagem, 3, 2)
+
+	m.remover(CamadaEntidades, "guarda")
+	if e := m.elementoEm(1, 1); e != ElementoPortal {
+		t.Errorf("depois de tirar o guarda, (1, 1) mostra %q, esperado o portal", e.simbolo)
+	}
+	m.remover(CamadaItens, "portal")
+	m.limpar(CamadaEntidades)
+	m.remover(CamadaItens, "inexistente")
+
+	if visiveis := m.compor(); len(visiveis) != 0 {
+		t.Errorf("objetos restantes: %v", visiveis)
+	}
+	if !reflect.DeepEqual(m.Terreno, antes) {
+		t.Errorf("terreno alterado pelas camadas: %v", m.Terreno)
+	}
+	if e := m.elementoEm(1, 1); e != Vegetacao {
+		t.Errorf("(1, 1) mostra %q, esperado a vegetação do terreno", e.simbolo)
+	}
+	if e := m.elementoEm(3, 2); e != Parede {
+		t.Errorf("(3, 2) mostra %q, esperado a parede do terreno", e.simbolo)
+	}
+}
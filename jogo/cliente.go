@@ -1,92 +1,109 @@
+//go:build !servidor
+
 package main
 
 import (
+    "errors"
+    "flag"
     "os"
     "log"
-    "fmt"
-    "net/rpc"
-    "time"
-    "math/rand"
-)
-// Constantes de Conexão
-const (
-    SERVER_ADDR = "localhost:1234" 
+    "path/filepath"
 )
+
 func main() {
-    //DEFINIR CLIENT ID E CONECTAR RPC
-    rand.Seed(time.Now().UnixNano())
-    clientID = fmt.Sprintf("Jogador-%d", rand.Intn(10000))
-    log.Printf("Iniciando Cliente: %s", clientID)
+    // Subcomando "validate": confere arquivos de mapa sem conectar ao servidor
+    if len(os.Args) > 1 && os.Args[1] == "validate" {
+        os.Exit(executarValidacao(os.Args[2:], os.Stdout))
+    }
+    // Subcomando "generate": grava um mapa gerado
+    if len(os.Args) > 1 && os.Args[1] == "generate" {
+        os.Exit(executarGeracao(os.Args[2:], os.Stdout))
+    }
+    // Subcomando "edit": editor de mapas no terminal
+    if len(os.Args) > 1 && os.Args[1] == "edit" {
+        os.Exit(executarEdicao(os.Args[2:], os.Stdout))
+    }
 
-    // Tenta conectar ao servidor RPC
-    client, err := rpc.Dial("tcp", SERVER_ADDR)
+    // Endereço do servidor, mapa e nome vêm das flags e do arquivo -config
+    cfg, err := configCarregar("jogo", os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        return
+    }
     if err != nil {
-        log.Fatalf("Falha ao conectar ao servidor RPC (%s). O servidor de jogo deve estar rodando: %v", SERVER_ADDR, err)
+        log.Fatal("Erro na configuração:", err)
+    }
+
+    // O nome é escolhido pelo jogador; o ClientID é atribuído pelo servidor no registro
+    nomeJogador = cfg.Nome
+    log.Printf("Iniciando Cliente: %s", nomeJogador)
+
+    // Tenta conectar ao servidor RPC
+    if err := clienteConectar(cfg.Endereco); err != nil {
+        log.Fatalf("Falha ao conectar ao servidor RPC (%s). O servidor de jogo deve estar rodando: %v", cfg.Endereco, err)
     }
-    clienteRPC = client 
     log.Println("Conexão RPC estabelecida.")
 
-    // REGISTRO NO SERVIDOR
-    sequence = 1 
-    registroComando := Comando{
-        ClientID: clientID,
-        SequenceNumber: sequence,
-        Acao: "register",
+    // LISTAGEM DE SALAS (não exige registro)
+    if cfg.ListarSalas {
+        if err := clienteListarSalas(); err != nil {
+            log.Fatalf("Falha ao listar as salas: %v", err)
+        }
+        return
+    }
+
+    // MODO ESPECTADOR (não registra nem envia comandos)
+    if cfg.Espectador {
+        executarEspectador(cfg)
+        return
     }
+
+    // REGISTRO NO SERVIDOR
     var resposta Resposta
-    
-    // Chamada RPC para registrar o jogador
-    err = clienteRPC.Call("JogoServer.ExecutarComando", registroComando, &resposta)
-    if err != nil || !resposta.Sucesso {
-        log.Fatalf("Falha ao registrar no servidor: %v, Resposta: %s", err, resposta.Mensagem)
+    if err := clienteRegistrar(clienteRPC, &resposta); err != nil {
+        log.Fatalf("Falha ao registrar no servidor: %v", err)
+    }
+    log.Println(resposta.Mensagem)
+
+    // ENTRADA NA SALA ESCOLHIDA (o registro leva à sala principal)
+    if cfg.Sala != "" && cfg.Sala != SalaPrincipal {
+        if !clienteTemRecurso(RecursoSalas) {
+            log.Fatalf("O servidor não oferece salas; não é possível entrar na sala %s.", cfg.Sala)
+        }
+        comando := Comando{
+            ClientID:       clienteID(),
+            SequenceNumber: proximaSequencia(),
+            Acao:           "join",
+            Sala:           &DadosSala{Nome: cfg.Sala},
+        }
+        if cfg.CriarSala {
+            comando.Acao = "create"
+            comando.Sala.Mapa = filepath.Base(cfg.Mapa)
+        }
+        var respostaSala Resposta
+        if err := clienteChamar("JogoServer.ExecutarComando", comando, &respostaSala); err != nil || !respostaSala.Ok() {
+            log.Fatalf("Falha ao entrar na sala %s: %v %s", cfg.Sala, err, respostaSala.Mensagem)
+        }
+        log.Println(respostaSala.Mensagem)
+        resposta = respostaSala
     }
-    log.Println("Registro no servidor concluído com sucesso.")
 
     // Inicializa a interface (termbox)
     interfaceIniciar()
     defer interfaceFinalizar()
 
-    // Usa "mapa.txt" como arquivo padrão ou lê o primeiro argumento
-    mapaFile := "mapa.txt"
-    if len(os.Args) > 1 {
-        mapaFile = os.Args[1]
-    }
-
-    // Inicializa o jogo (com posição inicial padrão do jogoNovo)
+    // O mapa é o da sala, enviado pelo servidor junto com o estado inicial
     jogo := jogoNovo()
-    if err := jogoCarregarMapa(mapaFile, &jogo); err != nil {
-        panic(err)
-    }
-
-// define posições iniciais (fixas ou aleatórias, como preferir)
-portal.X, portal.Y = 5, 5
-armadilha.X, armadilha.Y = 10, 10
-guarda.X, guarda.Y = 2, 2
-
-// insere os elementos no mapa principal
-withMapaLock(func() {
-    jogo.Mapa[portal.Y][portal.X] = portal.Elemento
-    jogo.Mapa[armadilha.Y][armadilha.X] = armadilha.Elemento
-    jogo.Mapa[guarda.Y][guarda.X] = guarda.Elemento
-})
-
-    // === INÍCIO DO BLOCO CRÍTICO: SINCRONIZAÇÃO PÓS-REGISTRO ===
-    // O servidor define a posição de spawn (ex: 3, 3). O cliente deve adotar essa posição.
-    if jogadorEstado, ok := resposta.EstadoAtual.Jogadores[clientID]; ok {
-        jogo.PosX = jogadorEstado.X
-        jogo.PosY = jogadorEstado.Y
-        jogo.Vidas = jogadorEstado.Vidas 
+    jogo.Conexao = "Servidor: conectado"
+    jogoAplicarResposta(&jogo, resposta)
+    if _, ok := jogo.Servidor.Jogadores[clienteJogadorID()]; ok {
         log.Printf("Estado inicial sincronizado com o servidor: X=%d, Y=%d, Vidas=%d", jogo.PosX, jogo.PosY, jogo.Vidas)
     } else {
         log.Println("Aviso: Jogador não encontrado no estado retornado pelo servidor após o registro.")
     }
-    // === FIM DO BLOCO CRÍTICO ===
-
-    // inicia o comportamento dos elementos 
-    iniciarElementos(&jogo)
 
     // INICIAR LOOP DE BUSCA DE ESTADO (DEVE SER CHAMADO APÓS A INICIALIZAÇÃO DO JOGO)
-    go loopAtualizacaoCliente(&jogo, clienteRPC, clientID)
+    // Guarda, portal e armadilha chegam junto com o estado do servidor.
+    iniciarElementos(&jogo)
 
     // Desenha o estado inicial do jogo
     interfaceDesenharJogo(&jogo)
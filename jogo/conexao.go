@@ -0,0 +1,253 @@
+// conexao.go - Conexão RPC do cliente com reconexão automática
+// Todas as chamadas ao servidor passam por clienteChamar. Quando a conexão cai,
+// o cliente refaz o Dial com backoff, retoma a sessão com o token de retomada e o
+// mesmo contador de sequência e ressincroniza posição e vidas com um snapshot completo.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// Limites do backoff entre tentativas de reconexão
+const (
+	atrasoReconexaoInicial = 250 * time.Millisecond
+	atrasoReconexaoMaximo  = 5 * time.Second
+)
+
+// errSemConexao é retornado enquanto a conexão com o servidor está sendo refeita.
+var errSemConexao = errors.New("sem conexão com o servidor")
+
+var (
+	conexaoMu    sync.Mutex // Protege clienteRPC, clientID, tokenSessao, jogadorID, sequence, reconectando, recursosAcordados e jogoAtual
+	reconectando bool
+
+	recursosAcordados map[string]bool // Recursos do protocolo acordados no registro (protocolo.go)
+	jogoAtual         *Jogo           // Jogo ressincronizado após cada reconexão
+
+	// Nonce dos registros deste cliente: um registro reenviado recebe a resposta original
+	nonceRegistro = novoIdentificador(16)
+)
+
+// clienteConectar faz a conexão inicial com o servidor.
+func clienteConectar(endereco string) error {
+	client, err := rpc.Dial("tcp", endereco)
+	if err != nil {
+		return err
+	}
+	conexaoMu.Lock()
+	enderecoServidor = endereco
+	clienteRPC = client
+	conexaoMu.Unlock()
+	return nil
+}
+
+// clienteID retorna o identificador da sessão atribuído pelo servidor a este cliente.
+func clienteID() string {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	return clientID
+}
+
+// clienteJogadorID retorna o identificador público do jogador local, com que ele
+// aparece no estado e nos eventos enviados pelo servidor.
+func clienteJogadorID() string {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	return jogadorID
+}
+
+// clienteToken retorna o token da sessão, enviado em todos os comandos.
+func clienteToken() string {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	return tokenSessao
+}
+
+// clienteDefinirJogo define o jogo ressincronizado e avisado pela reconexão. Deve ser
+// chamada antes de iniciar as goroutines que podem reconectar.
+func clienteDefinirJogo(jogo *Jogo) {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	jogoAtual = jogo
+}
+
+// clienteJogo retorna o jogo definido por clienteDefinirJogo, ou nil.
+func clienteJogo() *Jogo {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	return jogoAtual
+}
+
+// clienteRegistrar envia o "register" com o nome escolhido, os recursos suportados, o
+// nonce do cliente e, se houver, o token da sessão anterior, e adota o ClientID, o
+// token, o identificador público e os recursos acordados devolvidos pelo servidor. Um
+// servidor incompatível recusa o registro.
+func clienteRegistrar(client *rpc.Client, resposta *Resposta) error {
+	conexaoMu.Lock()
+	sequence++
+	comando := Comando{
+		ClientID:       clientID,
+		SequenceNumber: sequence,
+		Acao:           "register",
+		Versao:         VersaoProtocolo,
+		Token:          tokenSessao,
+		Registro:       &DadosRegistro{Nome: nomeJogador, Recursos: RecursosSuportados, Nonce: nonceRegistro},
+	}
+	conexaoMu.Unlock()
+
+	if err := client.Call("JogoServer.ExecutarComando", comando, resposta); err != nil {
+		return err
+	}
+	if !resposta.Ok() {
+		return errors.New(resposta.Mensagem)
+	}
+
+	conexaoMu.Lock()
+	clientID, tokenSessao, jogadorID = resposta.ClientID, resposta.Token, resposta.JogadorID
+	recursosAcordados = make(map[string]bool, len(resposta.Recursos))
+	for _, recurso := range resposta.Recursos {
+		recursosAcordados[recurso] = true
+	}
+	conexaoMu.Unlock()
+	return nil
+}
+
+// clienteTemRecurso indica se o recurso foi acordado com o servidor no registro.
+func clienteTemRecurso(recurso string) bool {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	return recursosAcordados[recurso]
+}
+
+// clienteListarSalas pede ao servidor a lista de salas e a imprime.
+func clienteListarSalas() error {
+	comando := Comando{SequenceNumber: proximaSequencia(), Acao: "rooms"}
+	var resposta Resposta
+	if err := clienteChamar("JogoServer.ExecutarComando", comando, &resposta); err != nil {
+		return err
+	}
+	fmt.Println(resposta.Mensagem)
+	for _, sala := range resposta.Salas {
+		fmt.Printf("  %-16s %-20s %d jogador(es)\n", sala.Nome, sala.Mapa, sala.Jogadores)
+	}
+	return nil
+}
+
+// proximaSequencia incrementa e retorna o número de sequência do próximo comando.
+func proximaSequencia() int {
+	conexaoMu.Lock()
+	defer conexaoMu.Unlock()
+	sequence++
+	return sequence
+}
+
+// clienteChamar faz uma chamada RPC ao servidor com a versão de comandos do cliente e o
+// token da sessão. Se a conexão tiver caído,
+// inicia a reconexão em segundo plano e retorna o erro para que o chamador
+// possa tentar novamente mais tarde.
+func clienteChamar(metodo string, comando Comando, resposta *Resposta) error {
+	conexaoMu.Lock()
+	client := clienteRPC
+	conexaoMu.Unlock()
+	if client == nil {
+		return errSemConexao
+	}
+
+	comando.Versao = VersaoProtocolo
+	comando.Token = clienteToken()
+	err := client.Call(metodo, comando, resposta)
+	if err != nil && conexaoPerdida(err) {
+		conexaoMu.Lock()
+		if clienteRPC == client {
+			clienteRPC = nil
+			client.Close()
+			if !reconectando {
+				reconectando = true
+				go clienteReconectar()
+			}
+		}
+		conexaoMu.Unlock()
+	}
+	return err
+}
+
+// conexaoPerdida indica se o erro veio da conexão e não de um erro retornado pelo servidor.
+func conexaoPerdida(err error) bool {
+	var erroServidor rpc.ServerError
+	return !errors.As(err, &erroServidor)
+}
+
+// clienteReconectar refaz a conexão com backoff exponencial e retoma a sessão.
+func clienteReconectar() {
+	atraso := atrasoReconexaoInicial
+	for tentativa := 1; ; tentativa++ {
+		jogoDefinirConexao(fmt.Sprintf("Servidor: reconectando (tentativa %d)...", tentativa))
+		time.Sleep(atraso)
+
+		client, err := rpc.Dial("tcp", enderecoServidor)
+		if err == nil {
+			if err = clienteRetomarSessao(client); err == nil {
+				conexaoMu.Lock()
+				clienteRPC = client
+				reconectando = false
+				conexaoMu.Unlock()
+				jogoDefinirConexao("Servidor: conectado")
+				return
+			}
+			client.Close()
+		}
+
+		atraso *= 2
+		if atraso > atrasoReconexaoMaximo {
+			atraso = atrasoReconexaoMaximo
+		}
+	}
+}
+
+// clienteRetomarSessao retoma a sessão com o token recebido no registro, continuando
+// o contador de sequência, e aplica o snapshot completo devolvido pelo servidor.
+// Se a sessão já tiver expirado, o servidor registra o jogador novamente.
+func clienteRetomarSessao(client *rpc.Client) error {
+	var resposta Resposta
+	if err := clienteRegistrar(client, &resposta); err != nil {
+		return err
+	}
+
+	if jogo := clienteJogo(); jogo != nil {
+		withMapaLock(func() {
+			jogoAplicarResposta(jogo, resposta)
+			jogo.GameOver = jogo.Vidas <= 0
+			jogo.StatusMsg = "Sessão retomada."
+		})
+	}
+	return nil
+}
+
+// clienteRenovarSessao registra o jogador de novo pela conexão atual quando o servidor
+// não reconhece mais a sessão (expirada ou com o token recusado), sem esperar uma
+// queda da conexão.
+func clienteRenovarSessao() error {
+	conexaoMu.Lock()
+	client := clienteRPC
+	conexaoMu.Unlock()
+	if client == nil {
+		return errSemConexao
+	}
+	return clienteRetomarSessao(client)
+}
+
+// jogoDefinirConexao atualiza o estado da conexão exibido na barra de status.
+func jogoDefinirConexao(status string) {
+	jogo := clienteJogo()
+	if jogo == nil {
+		return
+	}
+	withMapaLock(func() {
+		jogo.Conexao = status
+		interfaceDesenharJogo(jogo)
+	})
+}
@@ -0,0 +1,157 @@
+// config.go - Configuração compartilhada entre cliente e servidor
+// Os valores vêm, em ordem de prioridade crescente, dos padrões abaixo, de um
+// arquivo opcional (-config) com linhas "chave = valor" e das flags da linha de comando.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config reúne as opções usadas pelo cliente e pelo servidor.
+type Config struct {
+	Endereco       string        // Endereço do servidor usado pelo cliente (host:porta)
+	Porta          string        // Porta em que o servidor escuta
+	PortaGateway   string        // Porta do gateway JSON/WebSocket; vazia desativa (servidor)
+	Mapa           string        // Arquivo do mapa (servidor: sala principal; cliente: sala criada com -criar)
+	Nome           string        // Nome do jogador (cliente)
+	Sala           string        // Sala em que o cliente entra após o registro (cliente)
+	CriarSala      bool          // Cria a sala informada em Sala com o mapa Mapa (cliente)
+	ListarSalas    bool          // Lista as salas do servidor e sai (cliente)
+	Espectador     bool          // Assiste à sala informada em Sala sem entrar no jogo (cliente)
+	TaxaTick       int           // Ticks de simulação por segundo (servidor)
+	Semente        int64         // Semente da simulação (servidor)
+	SpawnX         int           // Coluna da posição inicial em mapas sem pontos de spawn ☺ (servidor)
+	SpawnY         int           // Linha da posição inicial em mapas sem pontos de spawn ☺ (servidor)
+	Gerador        string        // Gera o mapa da sala principal a cada rodada: "<tipo>" ou "labirinto:<algoritmo>" (servidor)
+	TimeoutSessao  time.Duration // Tempo sem contato até um jogador ser removido (servidor)
+	ContagemEspera time.Duration // Espera máxima após o primeiro jogador pronto (servidor)
+}
+
+// configPadrao retorna a configuração usada quando nada é informado.
+func configPadrao() Config {
+	return Config{
+		Endereco:       "localhost:1234",
+		Porta:          "1234",
+		PortaGateway:   PortaGatewayPadrao,
+		Mapa:           "mapa.txt",
+		TaxaTick:       TaxaTickPadrao,
+		Semente:        time.Now().UnixNano(),
+		SpawnX:         3,
+		SpawnY:         3,
+		TimeoutSessao:  TimeoutSessaoPadrao,
+		ContagemEspera: ContagemEsperaPadrao,
+	}
+}
+
+// configCarregar monta a configuração a partir dos argumentos da linha de comando.
+// Um argumento posicional, se houver, é usado como arquivo do mapa.
+func configCarregar(nome string, args []string) (Config, error) {
+	cfg := configPadrao()
+
+	// Primeira passagem: descobre apenas o arquivo de configuração
+	var arquivo string
+	fs := configFlags(nome, &cfg, &arquivo)
+	fs.SetOutput(io.Discard)
+	fs.Parse(args)
+	if arquivo != "" {
+		if err := configLerArquivo(arquivo, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Segunda passagem: as flags sobrescrevem os valores do arquivo
+	fs = configFlags(nome, &cfg, &arquivo)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		cfg.Mapa = fs.Arg(0)
+	}
+	return cfg, nil
+}
+
+// configFlags declara as flags ligadas aos campos da configuração.
+func configFlags(nome string, cfg *Config, arquivo *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(nome, flag.ContinueOnError)
+	fs.StringVar(arquivo, "config", *arquivo, "arquivo de configuração (linhas \"chave = valor\")")
+	fs.StringVar(&cfg.Endereco, "endereco", cfg.Endereco, "endereço do servidor (host:porta)")
+	fs.StringVar(&cfg.Porta, "porta", cfg.Porta, "porta em que o servidor escuta")
+	fs.StringVar(&cfg.PortaGateway, "porta-json", cfg.PortaGateway, "porta do gateway JSON-RPC/WebSocket (vazia: desativado)")
+	fs.StringVar(&cfg.Mapa, "mapa", cfg.Mapa, "arquivo do mapa")
+	fs.StringVar(&cfg.Nome, "nome", cfg.Nome, "nome exibido aos outros jogadores (vazio: escolhido pelo servidor)")
+	fs.StringVar(&cfg.Sala, "sala", cfg.Sala, "sala em que entrar (padrão: "+SalaPrincipal+")")
+	fs.BoolVar(&cfg.CriarSala, "criar", cfg.CriarSala, "cria a sala informada em -sala usando o mapa de -mapa")
+	fs.BoolVar(&cfg.ListarSalas, "salas", cfg.ListarSalas, "lista as salas do servidor e sai")
+	fs.BoolVar(&cfg.Espectador, "spectate", cfg.Espectador, "assiste à sala de -sala sem entrar no jogo (câmera livre)")
+	fs.IntVar(&cfg.TaxaTick, "tick", cfg.TaxaTick, "ticks de simulação por segundo")
+	fs.Int64Var(&cfg.Semente, "semente", cfg.Semente, "semente da simulação (para partidas reproduzíveis)")
+	fs.Var(spawnFlag{cfg}, "spawn", "posição inicial dos jogadores em mapas sem pontos de spawn ☺ (x,y)")
+	fs.StringVar(&cfg.Gerador, "gerar", cfg.Gerador, "gera um novo mapa da sala principal a cada rodada: labirinto[:algoritmo] ou masmorra (vazio: usa -mapa)")
+	fs.DurationVar(&cfg.TimeoutSessao, "timeout", cfg.TimeoutSessao, "tempo sem contato até um jogador ser removido")
+	fs.DurationVar(&cfg.ContagemEspera, "espera", cfg.ContagemEspera, "espera máxima pela rodada após o primeiro jogador ficar pronto")
+	return fs
+}
+
+// configLerArquivo aplica ao cfg as linhas "chave = valor" do arquivo.
+// Linhas vazias e iniciadas por '#' são ignoradas; as chaves são os nomes das flags.
+func configLerArquivo(nome string, cfg *Config) error {
+	arq, err := os.Open(nome)
+	if err != nil {
+		return err
+	}
+	defer arq.Close()
+
+	var ignorado string
+	fs := configFlags(nome, cfg, &ignorado)
+
+	scanner := bufio.NewScanner(arq)
+	for linha := 1; scanner.Scan(); linha++ {
+		texto := strings.TrimSpace(scanner.Text())
+		if texto == "" || strings.HasPrefix(texto, "#") {
+			continue
+		}
+		chave, valor, ok := strings.Cut(texto, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: esperado \"chave = valor\"", nome, linha)
+		}
+		chave, valor = strings.TrimSpace(chave), strings.TrimSpace(valor)
+		if chave == "config" || fs.Lookup(chave) == nil {
+			return fmt.Errorf("%s:%d: chave desconhecida %q", nome, linha, chave)
+		}
+		if err := fs.Set(chave, valor); err != nil {
+			return fmt.Errorf("%s:%d: %v", nome, linha, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// spawnFlag lê a posição inicial no formato "x,y".
+type spawnFlag struct{ cfg *Config }
+
+func (f spawnFlag) String() string {
+	if f.cfg == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d,%d", f.cfg.SpawnX, f.cfg.SpawnY)
+}
+
+func (f spawnFlag) Set(valor string) error {
+	xs, ys, ok := strings.Cut(valor, ",")
+	if !ok {
+		return fmt.Errorf("posição inválida %q (use x,y)", valor)
+	}
+	x, errX := strconv.Atoi(strings.TrimSpace(xs))
+	y, errY := strconv.Atoi(strings.TrimSpace(ys))
+	if errX != nil || errY != nil {
+		return fmt.Errorf("posição inválida %q (use x,y)", valor)
+	}
+	f.cfg.SpawnX, f.cfg.SpawnY = x, y
+	return nil
+}
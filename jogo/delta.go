@@ -0,0 +1,98 @@
+// delta.go - Cálculo e aplicação de deltas de estado entre ticks
+// Usado pelo servidor para enviar apenas o que mudou e pelo cliente para
+// reconstruir o estado completo a partir dos deltas recebidos.
+package main
+
+// deltaEntre calcula as mudanças necessárias para transformar o estado base no estado atual.
+func deltaEntre(base, atual EstadoJogo) DeltaEstado {
+	delta := DeltaEstado{
+		Sala:      atual.Sala,
+		Fase:      atual.Fase,
+		Contagem:  atual.Contagem,
+		TickBase:  base.Tick,
+		Tick:      atual.Tick,
+		Jogadores: make(map[string]EstadoJogador),
+		Entidades: make(map[string]EstadoEntidade),
+	}
+	for id, j := range atual.Jogadores {
+		if anterior, ok := base.Jogadores[id]; !ok || anterior != j {
+			delta.Jogadores[id] = j
+		}
+	}
+	for id := range base.Jogadores {
+		if _, ok := atual.Jogadores[id]; !ok {
+			delta.JogadoresRemovidos = append(delta.JogadoresRemovidos, id)
+		}
+	}
+	for id, e := range atual.Entidades {
+		if anterior, ok := base.Entidades[id]; !ok || anterior != e {
+			delta.Entidades[id] = e
+		}
+	}
+	for id := range base.Entidades {
+		if _, ok := atual.Entidades[id]; !ok {
+			delta.EntidadesRemovidas = append(delta.EntidadesRemovidas, id)
+		}
+	}
+	delta.FaseMudou = atual.Fase != base.Fase || atual.Contagem != base.Contagem
+	if atual.AvisoTick != base.AvisoTick {
+		delta.Aviso, delta.AvisoTick = atual.Aviso, atual.AvisoTick
+	}
+	delta.MapaTick = atual.MapaTick
+	if atual.MapaTick != base.MapaTick {
+		delta.Mapa = atual.Mapa
+	}
+	return delta
+}
+
+// snapshotCompleto transforma um estado em um delta completo.
+func snapshotCompleto(estado EstadoJogo) DeltaEstado {
+	delta := deltaEntre(EstadoJogo{}, estado)
+	delta.Completo = true
+	return delta
+}
+
+// aplicarDelta atualiza o estado com um delta recebido. Um delta só é aplicado
+// se for completo ou se partir exatamente do tick (e da sala) em que o estado está;
+// caso contrário retorna false e o estado não é alterado.
+func aplicarDelta(estado *EstadoJogo, delta DeltaEstado) bool {
+	if delta.Completo {
+		*estado = EstadoJogo{
+			Sala:      delta.Sala,
+			Jogadores: make(map[string]EstadoJogador),
+			Entidades: make(map[string]EstadoEntidade),
+		}
+	} else if delta.TickBase != estado.Tick || delta.Sala != estado.Sala || estado.Jogadores == nil {
+		return false
+	}
+
+	for id, j := range delta.Jogadores {
+		estado.Jogadores[id] = j
+	}
+	for _, id := range delta.JogadoresRemovidos {
+		delete(estado.Jogadores, id)
+	}
+	for id, e := range delta.Entidades {
+		estado.Entidades[id] = e
+	}
+	for _, id := range delta.EntidadesRemovidas {
+		delete(estado.Entidades, id)
+	}
+	if delta.AvisoTick != 0 {
+		estado.Aviso, estado.AvisoTick = delta.Aviso, delta.AvisoTick
+	}
+	if len(delta.Mapa) > 0 {
+		estado.Mapa = delta.Mapa
+	}
+	estado.MapaTick = delta.MapaTick
+	estado.Fase, estado.Contagem = delta.Fase, delta.Contagem
+	estado.Tick = delta.Tick
+	return true
+}
+
+// deltaVazio indica se o delta não traz nenhuma mudança.
+func deltaVazio(delta DeltaEstado) bool {
+	return !delta.Completo && len(delta.Jogadores) == 0 && len(delta.JogadoresRemovidos) == 0 &&
+		len(delta.Entidades) == 0 && len(delta.EntidadesRemovidas) == 0 && delta.AvisoTick == 0 &&
+		len(delta.Mapa) == 0 && !delta.FaseMudou
+}
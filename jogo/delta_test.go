@@ -0,0 +1,177 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// estadoTeste monta um estado da sala "principal" no tick informado.
+func estadoTeste(tick uint64, jogadores map[string]EstadoJogador, entidades map[string]EstadoEntidade) EstadoJogo {
+	if jogadores == nil {
+		jogadores = make(map[string]EstadoJogador)
+	}
+	if entidades == nil {
+		entidades = make(map[string]EstadoEntidade)
+	}
+	return EstadoJogo{Sala: SalaPrincipal, Fase: FaseJogo, Tick: tick, Jogadores: jogadores, Entidades: entidades}
+}
+
+func TestDeltaIdaEVolta(t *testing.T) {
+	ana := EstadoJogador{Nome: "Ana", X: 1, Y: 1, Vidas: 3}
+	bia := EstadoJogador{Nome: "Bia", X: 2, Y: 1, Vidas: 3}
+	guarda := EstadoEntidade{Tipo: TipoGuarda, X: 5, Y: 5}
+
+	casos := []struct {
+		nome        string
+		base, atual EstadoJogo
+	}{
+		{
+			nome:  "sem mudanças",
+			base:  estadoTeste(1, map[string]EstadoJogador{"a": ana}, nil),
+			atual: estadoTeste(2, map[string]EstadoJogador{"a": ana}, nil),
+		},
+		{
+			nome:  "jogador entra e se move",
+			base:  estadoTeste(1, map[string]EstadoJogador{"a": ana}, nil),
+			atual: estadoTeste(2, map[string]EstadoJogador{"a": {Nome: "Ana", X: 2, Y: 2, Vidas: 2}, "b": bia}, nil),
+		},
+		{
+			nome:  "jogador e entidade saem",
+			base:  estadoTeste(1, map[string]EstadoJogador{"a": ana, "b": bia}, map[string]EstadoEntidade{idGuarda: guarda}),
+			atual: estadoTeste(3, map[string]EstadoJogador{"b": bia}, nil),
+		},
+		{
+			nome:  "entidade surge e se move",
+			base:  estadoTeste(1, nil, map[string]EstadoEntidade{idPortal: {Tipo: TipoPortal, X: 1, Y: 2}}),
+			atual: estadoTeste(2, nil, map[string]EstadoEntidade{idPortal: {Tipo: TipoPortal, X: 3, Y: 4}, idGuarda: guarda}),
+		},
+		{
+			nome: "aviso, fase e contagem",
+			base: EstadoJogo{Sala: SalaPrincipal, Fase: FaseEspera, Contagem: 10, Tick: 1,
+				Jogadores: map[string]EstadoJogador{}, Entidades: map[string]EstadoEntidade{}},
+			atual: EstadoJogo{Sala: SalaPrincipal, Fase: FaseJogo, Tick: 2, Aviso: "Começou!", AvisoTick: 2,
+				Jogadores: map[string]EstadoJogador{}, Entidades: map[string]EstadoEntidade{}},
+		},
+		{
+			nome: "mapa gerado",
+			base: estadoTeste(1, nil, nil),
+			atual: EstadoJogo{Sala: SalaPrincipal, Fase: FaseJogo, Tick: 2, Mapa: []string{"▤▤▤", "▤☺▤", "▤▤▤"}, MapaTick: 2,
+				Jogadores: map[string]EstadoJogador{}, Entidades: map[string]EstadoEntidade{}},
+		},
+	}
+
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			estado := copiaTeste(c.base)
+			if !aplicarDelta(&estado, deltaEntre(c.base, c.atual)) {
+				t.Fatal("delta recusado pelo estado base")
+			}
+			if !reflect.DeepEqual(estado, c.atual) {
+				t.Errorf("estado reconstruído = %+v, esperado %+v", estado, c.atual)
+			}
+
+			// Um snapshot completo reconstrói o mesmo estado a partir de qualquer um
+			outro := estadoTeste(99, map[string]EstadoJogador{"x": ana}, nil)
+			if !aplicarDelta(&outro, snapshotCompleto(c.atual)) {
+				t.Fatal("snapshot completo recusado")
+			}
+			if !reflect.DeepEqual(outro, c.atual) {
+				t.Errorf("estado do snapshot = %+v, esperado %+v", outro, c.atual)
+			}
+		})
+	}
+}
+
+func TestDeltaBaseDiferente(t *testing.T) {
+	base := estadoTeste(5, map[string]EstadoJogador{"a": {Nome: "Ana", Vidas: 3}}, nil)
+	atual := estadoTeste(6, map[string]EstadoJogador{"a": {Nome: "Ana", X: 1, Vidas: 3}}, nil)
+	delta := deltaEntre(base, atual)
+
+	casos := []struct {
+		nome   string
+		estado EstadoJogo
+	}{
+		{"tick diferente", estadoTeste(4, nil, nil)},
+		{"outra sala", EstadoJogo{Sala: "arena", Tick: 5, Jogadores: map[string]EstadoJogador{}}},
+		{"estado vazio", EstadoJogo{Sala: SalaPrincipal, Tick: 5}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			antes := copiaTeste(c.estado)
+			if aplicarDelta(&c.estado, delta) {
+				t.Fatal("delta aplicado sobre um estado de outra base")
+			}
+			if !reflect.DeepEqual(c.estado, antes) {
+				t.Errorf("estado alterado por um delta recusado: %+v", c.estado)
+			}
+		})
+	}
+}
+
+func TestDeltaVazio(t *testing.T) {
+	base := estadoTeste(1, map[string]EstadoJogador{"a": {Nome: "Ana", Vidas: 3}}, nil)
+	casos := []struct {
+		nome  string
+		atual EstadoJogo
+		vazio bool
+	}{
+		{"mesmo estado em outro tick", estadoTeste(2, map[string]EstadoJogador{"a": {Nome: "Ana", Vidas: 3}}, nil), true},
+		{"jogador saiu", estadoTeste(2, nil, nil), false},
+		{"novo aviso", EstadoJogo{Sala: SalaPrincipal, Fase: FaseJogo, Tick: 2, AvisoTick: 2,
+			Jogadores: map[string]EstadoJogador{"a": {Nome: "Ana", Vidas: 3}}}, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			if got := deltaVazio(deltaEntre(base, c.atual)); got != c.vazio {
+				t.Errorf("deltaVazio = %v, esperado %v", got, c.vazio)
+			}
+		})
+	}
+	if deltaVazio(snapshotCompleto(base)) {
+		t.Error("snapshot completo considerado vazio")
+	}
+}
+
+// copiaTeste retorna uma cópia do estado que não compartilha os mapas.
+func copiaTeste(e EstadoJogo) EstadoJogo {
+	c := e
+	if e.Jogadores != nil {
+		c.Jogadores = make(map[string]EstadoJogador, len(e.Jogadores))
+		for id, j := range e.Jogadores {
+			c.Jogadores[id] = j
+		}
+	}
+	if e.Entidades != nil {
+		c.Entidades = make(map[string]EstadoEntidade, len(e.Entidades))
+		for id, en := range e.Entidades {
+			c.Entidades[id] = en
+		}
+	}
+	return c
+}
+
+func TestDeltaAvisoMostradoUmaVez(t *testing.T) {
+	jogo := jogoNovo()
+	estado := estadoTeste(2, nil, nil)
+	estado.Aviso, estado.AvisoTick = "Ana entrou no jogo.", 2
+	passos := []struct {
+		nome   string
+		delta  DeltaEstado
+		status string // Barra de status depois do delta; vazia se o aviso não aparecer
+	}{
+		{"aviso novo", snapshotCompleto(estado), estado.Aviso},
+		{"snapshot completo com o mesmo aviso", snapshotCompleto(estado), ""},
+		{"delta sem aviso", deltaEntre(estado, estadoTeste(3, nil, nil)), ""},
+		{"aviso mais novo", DeltaEstado{Sala: SalaPrincipal, TickBase: 3, Tick: 4, Aviso: "Bia entrou no jogo.", AvisoTick: 4},
+			"Bia entrou no jogo."},
+	}
+	for _, p := range passos {
+		jogo.StatusMsg = ""
+		if !jogoAplicarDelta(&jogo, p.delta) {
+			t.Fatalf("%s: delta recusado", p.nome)
+		}
+		if jogo.StatusMsg != p.status {
+			t.Errorf("%s: barra de status %q, esperado %q", p.nome, jogo.StatusMsg, p.status)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+//go:build !servidor
+
+// editor.go - Editor de mapas no terminal (subcomando "edit")
+// Abre um arquivo de mapa (ou cria um novo, cercado de paredes) e o desenha com os
+// mesmos símbolos e cores do jogo (interface.go). O cursor anda com WASD ou setas e
+// pinta com o pincel escolhido pelas teclas 1 a 8: paredes, vegetação, inimigos,
+// vazio, spawns, guardas, portais e armadilhas. Dois portais postos em sequência
+// formam um par. Toda alteração pode ser desfeita e refeita. Ao gravar, o mapa é
+// escrito no formato de mapa.go e só vai para o arquivo se passar pela validação:
+//
+//	./jogo edit maze.txt
+//	./jogo edit -largura 40 -altura 20 -nome Arena arena.txt
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// pincelEditor é uma ferramenta de pintura do editor.
+type pincelEditor struct {
+	nome string
+	tipo string // Tipo de célula pintado (mapa.go)
+}
+
+// Pincéis, na ordem das teclas 1 a 8
+var pinceisEditor = []pincelEditor{
+	{"parede", CelulaParede},
+	{"vegetação", CelulaVegetacao},
+	{"inimigo", CelulaInimigo},
+	{"vazio", CelulaVazio},
+	{"spawn", CelulaSpawn},
+	{"guarda", TipoGuarda},
+	{"portal", TipoPortal},
+	{"armadilha", TipoArmadilha},
+}
+
+// elementoMarca é o elemento desenhado sobre as células com spawn ou entidade.
+var elementoMarca = map[string]Elemento{
+	CelulaSpawn:   Personagem,
+	TipoGuarda:    ElementoGuarda,
+	TipoPortal:    ElementoPortal,
+	TipoArmadilha: ElementoArmadilha,
+}
+
+// Versões guardadas para desfazer
+const maxHistoricoEditor = 200
+
+// Largura reservada, à direita do mapa, para o painel do editor
+const larguraPainelEditor = 28
+
+// documentoMapa é o conteúdo do mapa em edição.
+type documentoMapa struct {
+	terreno      [][]Elemento
+	marcas       map[Posicao]string  // Spawn ou entidade de cada célula (no máximo um)
+	pares        map[Posicao]Posicao // Saída de cada portal ligado a outro
+	portalSemPar *Posicao            // Último portal posto sem par; o próximo se liga a ele
+	versao       int                 // Cada pintura gera uma versão nova; desfazer volta à anterior
+}
+
+// copia retorna uma cópia independente do documento, para o histórico.
+func (d documentoMapa) copia() documentoMapa {
+	c := documentoMapa{
+		terreno:      make([][]Elemento, len(d.terreno)),
+		marcas:       make(map[Posicao]string, len(d.marcas)),
+		pares:        make(map[Posicao]Posicao, len(d.pares)),
+		portalSemPar: d.portalSemPar,
+		versao:       d.versao,
+	}
+	for y, linha := range d.terreno {
+		c.terreno[y] = append([]Elemento(nil), linha...)
+	}
+	for p, marca := range d.marcas {
+		c.marcas[p] = marca
+	}
+	for p, saida := range d.pares {
+		c.pares[p] = saida
+	}
+	return c
+}
+
+// pintar aplica o tipo de célula na posição p. Paredes e inimigos removem o que
+// houver na célula; spawns e entidades limpam células bloqueadas e substituem a marca
+// anterior. Retorna false se nada mudou.
+func (d *documentoMapa) pintar(p Posicao, tipo string) bool {
+	antes, marcaAntes := d.terreno[p.Y][p.X], d.marcas[p]
+	if marcaAntes == tipo {
+		// A marca já está na célula: repintar um portal não pode desfazer o par dele
+		return false
+	}
+	if elementoMarca[tipo] == (Elemento{}) {
+		d.terreno[p.Y][p.X] = terrenoCelula[tipo]
+		if tipo == CelulaVazio || d.terreno[p.Y][p.X].tangivel {
+			d.removerMarca(p)
+		}
+		return d.terreno[p.Y][p.X] != antes || d.marcas[p] != marcaAntes
+	}
+
+	d.removerMarca(p)
+	if d.terreno[p.Y][p.X].tangivel {
+		d.terreno[p.Y][p.X] = Vazio
+	}
+	d.marcas[p] = tipo
+	if tipo == TipoPortal {
+		if d.portalSemPar != nil {
+			outro := *d.portalSemPar
+			d.pares[p], d.pares[outro] = outro, p
+			d.portalSemPar = nil
+		} else {
+			d.portalSemPar = &p
+		}
+	}
+	return true
+}
+
+// removerMarca tira o spawn ou a entidade da célula. O par de um portal removido
+// passa a ter a saída sorteada.
+func (d *documentoMapa) removerMarca(p Posicao) {
+	if saida, ok := d.pares[p]; ok {
+		delete(d.pares, p)
+		delete(d.pares, saida)
+	}
+	if d.portalSemPar != nil && *d.portalSemPar == p {
+		d.portalSemPar = nil
+	}
+	delete(d.marcas, p)
+}
+
+// mapaLido converte o documento para a forma lida de um arquivo, com spawns e
+// entidades em ordem de linha e coluna.
+func (d documentoMapa) mapaLido(info InfoMapa) mapaLido {
+	posicoes := make([]Posicao, 0, len(d.marcas))
+	for p := range d.marcas {
+		posicoes = append(posicoes, p)
+	}
+	sort.Slice(posicoes, func(i, j int) bool {
+		if posicoes[i].Y != posicoes[j].Y {
+			return posicoes[i].Y < posicoes[j].Y
+		}
+		return posicoes[i].X < posicoes[j].X
+	})
+
+	m := mapaLido{terreno: d.terreno}
+	m.info.Nome, m.info.Autor = info.Nome, info.Autor
+	for _, p := range posicoes {
+		switch d.marcas[p] {
+		case CelulaSpawn:
+			m.spawns = append(m.spawns, p)
+		case TipoGuarda:
+			m.info.Guardas = append(m.info.Guardas, p)
+		case TipoArmadilha:
+			m.info.Armadilhas = append(m.info.Armadilhas, p)
+		case TipoPortal:
+			portal := PortalMapa{Posicao: p}
+			if saida, ok := d.pares[p]; ok {
+				portal.Saida = &saida
+			}
+			m.info.Portais = append(m.info.Portais, portal)
+		}
+	}
+	return m
+}
+
+// camadas monta o mapa em camadas para o desenho: spawns como itens e as entidades
+// na camada do seu tipo (camadas.go), identificados pela posição.
+func (d documentoMapa) camadas() MapaCamadas {
+	m := MapaCamadas{Terreno: d.terreno}
+	for p, marca := range d.marcas {
+		camada, ok := camadaEntidade[marca]
+		if !ok {
+			camada = CamadaItens
+		}
+		m.definir(camada, fmt.Sprintf("%d,%d", p.X, p.Y), elementoMarca[marca], p.X, p.Y)
+	}
+	return m
+}
+
+// documentoDe monta o documento a partir de um mapa lido. Linhas mais curtas que a
+// mais larga são completadas com células vazias, para que a grade seja retangular.
+func documentoDe(m mapaLido) documentoMapa {
+	d := documentoMapa{marcas: make(map[Posicao]string), pares: make(map[Posicao]Posicao)}
+	largura := m.info.Largura
+	for _, linha := range m.terreno {
+		largura = max(largura, len(linha))
+	}
+	for _, linha := range m.terreno {
+		completa := append([]Elemento(nil), linha...)
+		for len(completa) < largura {
+			completa = append(completa, Vazio)
+		}
+		d.terreno = append(d.terreno, completa)
+	}
+
+	marcar := func(posicoes []Posicao, tipo string) {
+		for _, p := range posicoes {
+			if d.dentro(p) {
+				d.marcas[p] = tipo
+			}
+		}
+	}
+	marcar(m.spawns, CelulaSpawn)
+	marcar(m.info.Guardas, TipoGuarda)
+	marcar(m.info.Armadilhas, TipoArmadilha)
+	for _, portal := range m.info.Portais {
+		marcar([]Posicao{portal.Posicao}, TipoPortal)
+		if portal.Saida != nil && d.dentro(portal.Posicao) && d.dentro(*portal.Saida) {
+			d.pares[portal.Posicao] = *portal.Saida
+		}
+	}
+	return d
+}
+
+// documentoNovo cria um mapa vazio cercado de paredes, com um spawn no canto.
+func documentoNovo(largura, altura int) documentoMapa {
+	d := documentoMapa{marcas: make(map[Posicao]string), pares: make(map[Posicao]Posicao)}
+	for y := 0; y < altura; y++ {
+		linha := make([]Elemento, largura)
+		for x := range linha {
+			linha[x] = Vazio
+			if x == 0 || y == 0 || x == largura-1 || y == altura-1 {
+				linha[x] = Parede
+			}
+		}
+		d.terreno = append(d.terreno, linha)
+	}
+	d.marcas[Posicao{1, 1}] = CelulaSpawn
+	return d
+}
+
+func (d documentoMapa) dentro(p Posicao) bool {
+	return p.Y >= 0 && p.Y < len(d.terreno) && p.X >= 0 && p.X < len(d.terreno[p.Y])
+}
+
+// editor guarda o mapa em edição, o histórico e o estado da tela.
+type editor struct {
+	arquivo  string
+	info     InfoMapa // Nome e autor, preservados ao gravar
+	doc      documentoMapa
+	desfazer []documentoMapa
+	refazer  []documentoMapa
+
+	cursor           Posicao
+	pincel           int  // Índice em pinceisEditor
+	tracar           bool // Pinta a cada movimento do cursor
+	versoes          int  // Última versão criada por uma pintura
+	versaoGravada    int  // Versão do documento no arquivo; -1 num mapa novo
+	confirmarSaida   bool // ESC já foi pressionado uma vez com alterações não gravadas
+	status           string
+	cameraX, cameraY int
+}
+
+// executarEdicao trata o subcomando "edit" e retorna o código de saída do processo.
+func executarEdicao(args []string, saida io.Writer) int {
+	var largura, altura int
+	var nome, autor string
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	fs.SetOutput(saida)
+	fs.IntVar(&largura, "largura", 80, "largura de um mapa novo")
+	fs.IntVar(&altura, "altura", 30, "altura de um mapa novo")
+	fs.StringVar(&nome, "nome", "", "nome do mapa no cabeçalho")
+	fs.StringVar(&autor, "autor", "", "autor do mapa no cabeçalho")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(saida, "uso: edit [-largura L -altura A] [-nome N] [-autor A] <mapa.txt>")
+		return 2
+	}
+
+	e, err := editorAbrir(fs.Arg(0), largura, altura)
+	if err != nil {
+		fmt.Fprintln(saida, "Erro ao abrir o mapa:", err)
+		return 1
+	}
+	if nome != "" {
+		e.info.Nome = nome
+	}
+	if autor != "" {
+		e.info.Autor = autor
+	}
+
+	interfaceIniciar()
+	defer interfaceFinalizar()
+	for {
+		e.desenhar()
+		evento := interfaceLerEventoEditor()
+		if evento.Tipo == "sair" {
+			if !e.alterado() || e.confirmarSaida {
+				return 0
+			}
+			e.confirmarSaida = true
+			e.status = "Alterações não gravadas: G grava, ESC de novo sai sem gravar."
+			continue
+		}
+		e.confirmarSaida = false
+		e.executar(evento)
+	}
+}
+
+// editorAbrir carrega o mapa do arquivo, mesmo com problemas de validação (que aparecem
+// na barra de status), ou cria um novo se o arquivo não existir.
+func editorAbrir(arquivo string, largura, altura int) (*editor, error) {
+	e := &editor{arquivo: arquivo}
+	linhas, err := jogoLerArquivoMapa(arquivo)
+	if errors.Is(err, os.ErrNotExist) {
+		if largura < 3 || altura < 3 {
+			return nil, fmt.Errorf("tamanho %dx%d pequeno demais", largura, altura)
+		}
+		e.doc = documentoNovo(largura, altura)
+		e.cursor = Posicao{1, 1}
+		e.versaoGravada = -1
+		e.status = fmt.Sprintf("Novo mapa %dx%d.", largura, altura)
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := mapaInterpretar(linhas)
+	if len(m.terreno) == 0 {
+		return nil, fmt.Errorf("%s: mapa sem grade", arquivo)
+	}
+	e.info = m.info
+	e.doc = documentoDe(m)
+	e.status = fmt.Sprintf("%s: %dx%d.", arquivo, len(e.doc.terreno[0]), len(e.doc.terreno))
+	var erros ErrosMapa
+	if errors.As(err, &erros) {
+		e.status = fmt.Sprintf("%s: %d problema(s); o primeiro: %v", arquivo, len(erros), erros[0])
+	}
+	if len(m.spawns) > 0 && e.doc.dentro(m.spawns[0]) {
+		e.cursor = m.spawns[0]
+	}
+	return e, nil
+}
+
+// executar aplica um evento do teclado ao editor.
+func (e *editor) executar(evento EventoTeclado) {
+	switch evento.Tipo {
+	case "cursor":
+		dx, dy, _ := direcaoParaDelta(string(evento.Tecla))
+		if p := (Posicao{e.cursor.X + dx, e.cursor.Y + dy}); e.doc.dentro(p) {
+			e.cursor = p
+			if e.tracar {
+				e.pintar(pinceisEditor[e.pincel].tipo)
+			}
+		}
+	case "pincel":
+		e.pincel = int(evento.Tecla - '1')
+		e.status = "Pincel: " + pinceisEditor[e.pincel].nome
+	case "pintar":
+		e.pintar(pinceisEditor[e.pincel].tipo)
+	case "apagar":
+		e.pintar(CelulaVazio)
+	case "tracar":
+		e.tracar = !e.tracar
+		if e.tracar {
+			e.pintar(pinceisEditor[e.pincel].tipo)
+		}
+	case "desfazer":
+		e.voltar(&e.desfazer, &e.refazer, "Desfeito.", "Nada a desfazer.")
+	case "refazer":
+		e.voltar(&e.refazer, &e.desfazer, "Refeito.", "Nada a refazer.")
+	case "validar":
+		if _, err := e.conferir(); err == nil {
+			e.status = "Mapa válido."
+		}
+	case "salvar":
+		e.gravar()
+	}
+}
+
+// pintar aplica o tipo de célula sob o cursor, guardando a versão anterior para desfazer.
+func (e *editor) pintar(tipo string) {
+	anterior := e.doc.copia()
+	if !e.doc.pintar(e.cursor, tipo) {
+		return
+	}
+	e.desfazer = append(e.desfazer, anterior)
+	if len(e.desfazer) > maxHistoricoEditor {
+		e.desfazer = e.desfazer[1:]
+	}
+	e.refazer = nil
+	e.versoes++
+	e.doc.versao = e.versoes
+}
+
+// voltar troca o documento pela última versão de origem, guardando o atual em destino.
+func (e *editor) voltar(origem, destino *[]documentoMapa, feito, vazio string) {
+	if len(*origem) == 0 {
+		e.status = vazio
+		return
+	}
+	*destino = append(*destino, e.doc)
+	e.doc = (*origem)[len(*origem)-1]
+	*origem = (*origem)[:len(*origem)-1]
+	e.status = feito
+}
+
+// alterado indica se o documento difere do gravado no arquivo. Desfazer até a versão
+// gravada volta a deixá-lo sem alterações.
+func (e *editor) alterado() bool {
+	return e.doc.versao != e.versaoGravada
+}
+
+// conferir escreve o mapa e o valida como se fosse lido do arquivo. Se houver problemas,
+// mostra o primeiro na barra de status e leva o cursor até ele, quando está na grade.
+func (e *editor) conferir() ([]string, error) {
+	linhas := mapaEscrever(e.doc.mapaLido(e.info))
+	m, err := mapaInterpretar(linhas)
+	var erros ErrosMapa
+	if !errors.As(err, &erros) {
+		return linhas, err
+	}
+	primeiro := erros[0]
+	e.status = fmt.Sprintf("%d problema(s); o primeiro: %s", len(erros), primeiro.Msg)
+	if p := (Posicao{primeiro.Coluna - 1, primeiro.Linha - m.info.LinhaGrade}); primeiro.Coluna > 0 && e.doc.dentro(p) {
+		e.cursor = p
+	}
+	return nil, err
+}
+
+// gravar valida o mapa e, se estiver correto, o grava no arquivo.
+func (e *editor) gravar() {
+	linhas, err := e.conferir()
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(e.arquivo, []byte(strings.Join(linhas, "\n")+"\n"), 0o644); err != nil {
+		e.status = "Erro ao gravar: " + err.Error()
+		return
+	}
+	e.versaoGravada = e.doc.versao
+	e.status = "Gravado em " + e.arquivo + "."
+}
+
+// desenhar mostra o mapa, o cursor, o painel à direita e a barra de status.
+func (e *editor) desenhar() {
+	interfaceLimparTela()
+	larguraTela, alturaTela := interfaceTamanhoTela()
+	e.seguirCursor(max(larguraTela-larguraPainelEditor, 1), max(alturaTela-3, 1))
+
+	mapa := e.doc.camadas()
+	cursor := interfaceRealcar(mapa.elementoEm(e.cursor.X, e.cursor.Y))
+	mapa.definir(CamadaSobreposicao, "cursor", cursor, e.cursor.X, e.cursor.Y)
+	interfaceDesenharCamadas(&mapa, e.cameraX, e.cameraY)
+
+	// Painel: pincéis, célula sob o cursor e modo de traço
+	coluna := min(len(e.doc.terreno[0])-e.cameraX, larguraTela-larguraPainelEditor) + 2
+	titulo := "Editor: " + e.arquivo
+	if e.alterado() {
+		titulo += " *"
+	}
+	interfaceEscrever(coluna, 0, titulo, CorTexto)
+	for i, p := range pinceisEditor {
+		cor := CorTexto
+		if i == e.pincel {
+			cor = CorDestaque
+		}
+		interfaceEscrever(coluna, i+2, fmt.Sprintf("%d %s", i+1, p.nome), cor)
+	}
+	interfaceEscrever(coluna, len(pinceisEditor)+3, fmt.Sprintf("Cursor (%d, %d)", e.cursor.X, e.cursor.Y), CorTexto)
+	if marca, ok := e.doc.marcas[e.cursor]; ok {
+		descricao := marca
+		if saida, ok := e.doc.pares[e.cursor]; ok {
+			descricao += fmt.Sprintf(" → (%d, %d)", saida.X, saida.Y)
+		}
+		interfaceEscrever(coluna, len(pinceisEditor)+4, descricao, CorTexto)
+	}
+	if e.tracar {
+		interfaceEscrever(coluna, len(pinceisEditor)+5, "Traço ligado (P)", CorTexto)
+	}
+
+	base := min(len(e.doc.terreno)-e.cameraY, alturaTela-3)
+	interfaceEscrever(0, base+1, e.status, CorTexto)
+	interfaceEscrever(0, base+2, "WASD/setas: cursor  1-8: pincel  ESPAÇO: pintar  X: apagar  P: traço  U/R: desfazer/refazer  V: validar  G: gravar  ESC: sair", CorTexto)
+	interfaceAtualizarTela()
+}
+
+// seguirCursor desloca a câmera para manter o cursor na área visível do mapa.
+func (e *editor) seguirCursor(largura, altura int) {
+	if e.cursor.X < e.cameraX {
+		e.cameraX = e.cursor.X
+	} else if e.cursor.X >= e.cameraX+largura {
+		e.cameraX = e.cursor.X - largura + 1
+	}
+	if e.cursor.Y < e.cameraY {
+		e.cameraY = e.cursor.Y
+	} else if e.cursor.Y >= e.cameraY+altura {
+		e.cameraY = e.cursor.Y - altura + 1
+	}
+}
@@ -0,0 +1,237 @@
+//go:build !servidor
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditorPortais(t *testing.T) {
+	a, b, c, d := Posicao{1, 1}, Posicao{3, 1}, Posicao{1, 2}, Posicao{3, 2}
+	type pintura struct {
+		p    Posicao
+		tipo string
+	}
+	casos := []struct {
+		nome     string
+		pinturas []pintura
+		pares    map[Posicao]Posicao
+		semPar   *Posicao // Portal que espera o próximo para formar o par
+	}{
+		{
+			nome:     "dois portais em sequência formam um par",
+			pinturas: []pintura{{a, TipoPortal}, {b, TipoPortal}},
+			pares:    map[Posicao]Posicao{a: b, b: a},
+		},
+		{
+			nome:     "o terceiro portal espera o quarto",
+			pinturas: []pintura{{a, TipoPortal}, {b, TipoPortal}, {c, TipoPortal}},
+			pares:    map[Posicao]Posicao{a: b, b: a},
+			semPar:   &c,
+		},
+		{
+			nome:     "outras marcas entre os portais não desfazem a espera",
+			pinturas: []pintura{{a, TipoPortal}, {c, TipoGuarda}, {b, TipoPortal}},
+			pares:    map[Posicao]Posicao{a: b, b: a},
+		},
+		{
+			nome:     "parede sobre um portal deixa o outro com saída sorteada",
+			pinturas: []pintura{{a, TipoPortal}, {b, TipoPortal}, {a, CelulaParede}, {c, TipoPortal}},
+			pares:    map[Posicao]Posicao{},
+			semPar:   &c,
+		},
+		{
+			nome:     "armadilha sobre o portal sem par cancela a espera",
+			pinturas: []pintura{{a, TipoPortal}, {a, TipoArmadilha}, {b, TipoPortal}, {c, TipoPortal}},
+			pares:    map[Posicao]Posicao{b: c, c: b},
+		},
+		{
+			nome:     "repintar um portal do par não o desfaz",
+			pinturas: []pintura{{a, TipoPortal}, {b, TipoPortal}, {a, TipoPortal}, {c, TipoPortal}},
+			pares:    map[Posicao]Posicao{a: b, b: a},
+			semPar:   &c,
+		},
+		{
+			nome:     "apagar um portal do par e formar outro",
+			pinturas: []pintura{{a, TipoPortal}, {b, TipoPortal}, {b, CelulaVazio}, {c, TipoPortal}, {d, TipoPortal}},
+			pares:    map[Posicao]Posicao{c: d, d: c},
+		},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			doc := documentoNovo(5, 4)
+			for _, p := range caso.pinturas {
+				doc.pintar(p.p, p.tipo)
+			}
+			if !reflect.DeepEqual(doc.pares, caso.pares) {
+				t.Errorf("pares = %v, esperado %v", doc.pares, caso.pares)
+			}
+			if !reflect.DeepEqual(doc.portalSemPar, caso.semPar) {
+				t.Errorf("portal sem par = %v, esperado %v", doc.portalSemPar, caso.semPar)
+			}
+			for p := range doc.pares {
+				if doc.marcas[p] != TipoPortal {
+					t.Errorf("par em %v, que não é portal (%q)", p, doc.marcas[p])
+				}
+			}
+		})
+	}
+}
+
+func TestEditorRemoverMarca(t *testing.T) {
+	doc := documentoNovo(5, 4)
+	spawn := Posicao{1, 1}
+
+	// Parede sobre o spawn remove a marca; spawn sobre a parede limpa a célula
+	if !doc.pintar(spawn, CelulaParede) {
+		t.Fatal("parede sobre o spawn não mudou nada")
+	}
+	if _, ok := doc.marcas[spawn]; ok || doc.terreno[1][1] != Parede {
+		t.Errorf("célula = %q com marca %q, esperado parede sem marca", doc.terreno[1][1].simbolo, doc.marcas[spawn])
+	}
+	doc.pintar(spawn, CelulaSpawn)
+	if doc.marcas[spawn] != CelulaSpawn || doc.terreno[1][1] != Vazio {
+		t.Errorf("célula = %q com marca %q, esperado spawn sobre vazio", doc.terreno[1][1].simbolo, doc.marcas[spawn])
+	}
+
+	// Vegetação não é bloqueio: a marca fica
+	doc.pintar(spawn, CelulaVegetacao)
+	if doc.marcas[spawn] != CelulaSpawn {
+		t.Errorf("vegetação removeu o spawn")
+	}
+	if doc.pintar(spawn, CelulaVegetacao) || doc.pintar(spawn, CelulaSpawn) {
+		t.Error("pintar o mesmo tipo de novo mudou o documento")
+	}
+}
+
+func TestEditorDesfazerRefazer(t *testing.T) {
+	e := &editor{doc: documentoNovo(5, 4), cursor: Posicao{2, 2}}
+	vazio := e.doc.copia()
+	e.pintar(CelulaParede)
+	comParede := e.doc.copia()
+	e.cursor = Posicao{3, 2}
+	e.pintar(TipoGuarda)
+	comGuarda := e.doc.copia()
+	e.pintar(TipoGuarda) // Sem mudança: não entra no histórico
+
+	passos := []struct {
+		acao   string
+		doc    documentoMapa
+		status string
+	}{
+		{"desfazer", comParede, "Desfeito."},
+		{"desfazer", vazio, "Desfeito."},
+		{"desfazer", vazio, "Nada a desfazer."},
+		{"refazer", comParede, "Refeito."},
+		{"refazer", comGuarda, "Refeito."},
+		{"refazer", comGuarda, "Nada a refazer."},
+		{"desfazer", comParede, "Desfeito."},
+	}
+	for i, p := range passos {
+		e.executar(EventoTeclado{Tipo: p.acao})
+		if !reflect.DeepEqual(e.doc, p.doc) || e.status != p.status {
+			t.Fatalf("passo %d (%s): status %q, documento diferente do esperado: %v", i, p.acao, e.status, !reflect.DeepEqual(e.doc, p.doc))
+		}
+	}
+
+	// Uma pintura nova descarta o que havia para refazer
+	e.cursor = Posicao{1, 2}
+	e.pintar(CelulaInimigo)
+	if e.executar(EventoTeclado{Tipo: "refazer"}); e.status != "Nada a refazer." {
+		t.Errorf("refazer depois de pintar: %q", e.status)
+	}
+}
+
+func TestEditorIdaEVolta(t *testing.T) {
+	// O documento gravado (mapaEscrever) e lido de novo (mapaInterpretar) volta igual
+	e := &editor{doc: documentoNovo(8, 5), info: InfoMapa{Nome: "Teste", Autor: "Ana"}}
+	pinturas := []struct {
+		p    Posicao
+		tipo string
+	}{
+		{Posicao{3, 1}, CelulaParede},
+		{Posicao{4, 1}, CelulaVegetacao},
+		{Posicao{5, 3}, CelulaInimigo},
+		{Posicao{2, 3}, CelulaSpawn},
+		{Posicao{6, 1}, TipoGuarda},
+		{Posicao{1, 3}, TipoPortal},
+		{Posicao{6, 3}, TipoPortal},
+		{Posicao{3, 2}, TipoPortal}, // Sem par: saída sorteada
+		{Posicao{4, 3}, TipoArmadilha},
+		{Posicao{4, 1}, TipoArmadilha}, // Sobre a vegetação
+	}
+	for _, p := range pinturas {
+		e.cursor = p.p
+		e.pintar(p.tipo)
+	}
+
+	linhas, err := e.conferir()
+	if err != nil {
+		t.Fatalf("mapa recusado: %v", err)
+	}
+	m, err := mapaInterpretar(linhas)
+	if err != nil {
+		t.Fatalf("mapa escrito recusado: %v\n%v", err, linhas)
+	}
+	lido := documentoDe(m)
+	if !reflect.DeepEqual(lido.terreno, e.doc.terreno) {
+		t.Errorf("terreno lido difere do escrito:\n%v", linhas)
+	}
+	if !reflect.DeepEqual(lido.marcas, e.doc.marcas) {
+		t.Errorf("marcas = %v, esperado %v", lido.marcas, e.doc.marcas)
+	}
+	if !reflect.DeepEqual(lido.pares, e.doc.pares) {
+		t.Errorf("pares = %v, esperado %v", lido.pares, e.doc.pares)
+	}
+	if m.info.Nome != "Teste" || m.info.Autor != "Ana" {
+		t.Errorf("cabeçalho com nome %q e autor %q", m.info.Nome, m.info.Autor)
+	}
+	if !reflect.DeepEqual(mapaEscrever(m), linhas) {
+		t.Errorf("reescrita difere:\n%v\n%v", mapaEscrever(m), linhas)
+	}
+}
+
+func TestEditorAlteradoAposGravar(t *testing.T) {
+	arquivo := filepath.Join(t.TempDir(), "novo.txt")
+	e, err := editorAbrir(arquivo, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.alterado() {
+		t.Error("mapa novo, ainda sem arquivo, aparece como gravado")
+	}
+	e.cursor = Posicao{2, 2}
+	e.pintar(CelulaParede)
+	if e.executar(EventoTeclado{Tipo: "salvar"}); e.alterado() {
+		t.Fatalf("alterado depois de gravar: %q", e.status)
+	}
+
+	// Desfazer e refazer passam pela versão gravada
+	e.cursor = Posicao{3, 2}
+	e.pintar(TipoGuarda)
+	passos := []struct {
+		acao     string
+		alterado bool
+	}{
+		{"desfazer", false},
+		{"desfazer", true},
+		{"refazer", false},
+		{"refazer", true},
+		{"desfazer", false},
+	}
+	for i, p := range passos {
+		if e.executar(EventoTeclado{Tipo: p.acao}); e.alterado() != p.alterado {
+			t.Errorf("passo %d (%s): alterado = %v, esperado %v", i, p.acao, e.alterado(), p.alterado)
+		}
+	}
+
+	// Pintar de novo o que foi desfeito é outra versão, mesmo com o mesmo conteúdo
+	e.executar(EventoTeclado{Tipo: "desfazer"})
+	e.cursor = Posicao{2, 2}
+	e.pintar(CelulaParede)
+	if !e.alterado() {
+		t.Error("pintura depois de desfazer aparece como gravada")
+	}
+}
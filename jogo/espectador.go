@@ -0,0 +1,237 @@
+//go:build !servidor
+
+// espectador.go - Modo espectador do cliente de terminal (-spectate)
+// O espectador não se registra e nunca chama ExecutarComando: pede ao servidor o
+// mapa e o estado da sala (Assistir) e se inscreve no stream de espectador dela
+// ("ASSISTIR <sala>"), recebendo um snapshot a cada atualização. Ele não aparece
+// para os jogadores. A câmera é livre (WASD ou setas) e TAB passa para a próxima sala.
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// espectador guarda a sala assistida e o stream aberto para ela.
+type espectador struct {
+	jogo  *Jogo
+	mu    sync.Mutex
+	sala  string
+	salas []InfoSala
+	parar chan struct{} // Fechado para encerrar o stream da sala atual
+}
+
+// executarEspectador assiste à sala da configuração (ou à principal) até o ESC.
+func executarEspectador(cfg Config) {
+	jogo := jogoNovo()
+	jogo.Espectador = true
+	clienteDefinirJogo(&jogo)
+	e := &espectador{jogo: &jogo}
+
+	sala := cfg.Sala
+	if sala == "" {
+		sala = SalaPrincipal
+	}
+	interfaceIniciar()
+	defer interfaceFinalizar()
+	if err := e.assistir(sala); err != nil {
+		interfaceFinalizar()
+		log.Fatalf("Falha ao assistir à sala %s: %v", sala, err)
+	}
+
+	for {
+		evento := interfaceLerEventoTeclado()
+		switch evento.Tipo {
+		case "sair":
+			return
+		case "mover", "camera":
+			withMapaLock(func() {
+				espectadorMoverCamera(&jogo, evento.Tecla)
+				interfaceDesenharJogo(&jogo)
+			})
+		case "proximaSala":
+			if err := e.assistir(e.proximaSala()); err != nil {
+				withMapaLock(func() {
+					jogo.StatusMsg = "Falha ao trocar de sala: " + err.Error()
+					interfaceDesenharJogo(&jogo)
+				})
+			}
+		}
+	}
+}
+
+// espectadorMoverCamera desloca a câmera uma célula na direção da tecla (WASD).
+// Deve ser chamada com o mapa travado.
+func espectadorMoverCamera(jogo *Jogo, tecla rune) {
+	dx, dy, ok := direcaoParaDelta(string(tecla))
+	if !ok {
+		return
+	}
+	jogo.CameraX += dx
+	jogo.CameraY += dy
+}
+
+// assistir troca para a sala informada: busca o mapa e o estado dela e reabre o stream.
+func (e *espectador) assistir(nome string) error {
+	resposta, err := espectadorChamar(nome)
+	if err != nil {
+		return err
+	}
+	if !resposta.Ok() {
+		return fmt.Errorf("%s", resposta.Mensagem)
+	}
+
+	e.mu.Lock()
+	if e.parar != nil {
+		close(e.parar)
+	}
+	e.sala, e.salas = resposta.Sala, resposta.Salas
+	e.parar = make(chan struct{})
+	parar := e.parar
+	e.mu.Unlock()
+
+	withMapaLock(func() {
+		jogoAplicarResposta(e.jogo, resposta)
+		e.jogo.CameraX, e.jogo.CameraY = 0, 0
+		e.jogo.StatusMsg = resposta.Mensagem
+		interfaceDesenharJogo(e.jogo)
+	})
+	go e.loopStream(resposta.Sala, parar)
+	return nil
+}
+
+// proximaSala retorna a sala seguinte à atual na lista recebida do servidor.
+func (e *espectador) proximaSala() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, sala := range e.salas {
+		if sala.Nome == e.sala {
+			return e.salas[(i+1)%len(e.salas)].Nome
+		}
+	}
+	return SalaPrincipal
+}
+
+// loopStream recebe os snapshots da sala até parar ser fechado, reconectando com
+// backoff se a conexão cair. Se o servidor tiver removido a sala, para de tentar.
+func (e *espectador) loopStream(sala string, parar chan struct{}) {
+	atraso := atrasoReconexaoInicial
+	for {
+		conn, err := net.Dial("tcp", enderecoServidor)
+		if err == nil {
+			atraso = atrasoReconexaoInicial
+			fim := make(chan struct{}) // Fechado quando a conexão deixa de entregar snapshots
+			go func() {
+				defer close(fim)
+				e.receberStream(conn, sala, parar)
+			}()
+			select {
+			case <-parar:
+			case <-fim:
+			}
+			conn.Close()
+			<-fim
+		}
+		if fechado(parar) || e.salaRemovida(sala, parar) {
+			return
+		}
+		jogoDefinirConexao("Servidor: reconectando...")
+		time.Sleep(atraso)
+		atraso *= 2
+		if atraso > atrasoReconexaoMaximo {
+			atraso = atrasoReconexaoMaximo
+		}
+	}
+}
+
+// salaRemovida confere, depois que o stream terminou, se a sala assistida ainda existe:
+// o servidor encerra o stream de uma sala removida por ter ficado vazia. Nesse caso
+// avisa na barra de status e atualiza a lista usada pelo TAB.
+func (e *espectador) salaRemovida(sala string, parar chan struct{}) bool {
+	resposta, err := espectadorChamar(sala)
+	if err != nil || resposta.Motivo != MotivoSalaNaoEncontrada {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if fechado(parar) {
+		// Já trocou de sala enquanto conferia
+		return true
+	}
+	e.salas = resposta.Salas
+	withMapaLock(func() {
+		e.jogo.Conexao = "Servidor: conectado (espectador)"
+		e.jogo.StatusMsg = fmt.Sprintf("A sala %s foi removida. TAB passa para outra sala.", sala)
+		interfaceDesenharJogo(e.jogo)
+	})
+	return true
+}
+
+// receberStream inscreve o espectador na sala e aplica os snapshots recebidos
+// até a conexão ser encerrada.
+func (e *espectador) receberStream(conn net.Conn, sala string, parar chan struct{}) {
+	if _, err := fmt.Fprintf(conn, "%s%s\n", PrefixoEspectador, sala); err != nil {
+		return
+	}
+	jogoDefinirConexao("Servidor: conectado (espectador)")
+	dec := gob.NewDecoder(conn)
+	for {
+		var delta DeltaEstado
+		if err := dec.Decode(&delta); err != nil {
+			return
+		}
+		withMapaLock(func() {
+			// Snapshots atrasados de uma sala anterior são descartados
+			if delta.Sala == sala && !fechado(parar) {
+				jogoAplicarDelta(e.jogo, delta)
+				interfaceDesenharJogo(e.jogo)
+			}
+		})
+	}
+}
+
+// espectadorChamar chama Assistir. Sem sessão a retomar, uma conexão perdida (ou
+// ainda não feita) é apenas refeita.
+func espectadorChamar(sala string) (Resposta, error) {
+	comando := Comando{Versao: VersaoProtocolo, Sala: &DadosSala{Nome: sala}}
+	var resposta Resposta
+
+	conexaoMu.Lock()
+	client := clienteRPC
+	conexaoMu.Unlock()
+	if client != nil {
+		err := client.Call("JogoServer.Assistir", comando, &resposta)
+		if err == nil || !conexaoPerdida(err) {
+			return resposta, err
+		}
+	}
+
+	novo, err := rpc.Dial("tcp", enderecoServidor)
+	if err != nil {
+		return resposta, err
+	}
+	conexaoMu.Lock()
+	clienteRPC = novo
+	conexaoMu.Unlock()
+	if client != nil {
+		client.Close()
+	}
+	err = novo.Call("JogoServer.Assistir", comando, &resposta)
+	return resposta, err
+}
+
+// fechado indica se o canal já foi fechado.
+func fechado(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,292 @@
+// Package gerador cria mapas procedurais no formato dos arquivos de mapa do jogo
+// (cabeçalho e grade, ver mapa.go no pacote principal): labirintos, por vários
+// algoritmos (labirinto.go), e masmorras de salas ligadas por corredores
+// (masmorra.go), com manchas de vegetação, inimigos, spawns, guardas, portais e
+// armadilhas. As mesmas opções, com a mesma semente, produzem sempre o mesmo mapa.
+//
+// Todo mapa gerado é retangular e conexo: inimigos só são postos onde não
+// separam partes do mapa, e spawns e entidades ficam em células livres distintas.
+package gerador
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Símbolos da legenda padrão do jogo usados na grade
+const (
+	simboloParede    = '▤'
+	simboloVazio     = ' '
+	simboloVegetacao = '♣'
+	simboloInimigo   = '☠'
+)
+
+// Tipos de mapa
+const (
+	TipoLabirinto = "labirinto"
+	TipoMasmorra  = "masmorra"
+)
+
+// Algoritmos de labirinto
+const (
+	AlgoritmoBacktracking = "backtracking" // Busca em profundidade: corredores longos e sinuosos
+	AlgoritmoPrim         = "prim"         // Prim aleatório: muitos becos curtos
+	AlgoritmoKruskal      = "kruskal"      // Kruskal aleatório: ramificações uniformes
+	AlgoritmoDivisao      = "divisao"      // Divisão recursiva: paredes longas e retas
+)
+
+// Tipos e Algoritmos listam os valores aceitos em Opcoes.Tipo e Opcoes.Algoritmo.
+var (
+	Tipos      = []string{TipoLabirinto, TipoMasmorra}
+	Algoritmos = []string{AlgoritmoBacktracking, AlgoritmoPrim, AlgoritmoKruskal, AlgoritmoDivisao}
+)
+
+// Tamanho mínimo de um mapa gerado, em células
+const tamanhoMinimo = 7
+
+// Opcoes descreve o mapa a gerar.
+type Opcoes struct {
+	Tipo       string // TipoLabirinto ou TipoMasmorra
+	Algoritmo  string // Algoritmo do labirinto (ignorado nas masmorras)
+	Largura    int
+	Altura     int
+	Semente    int64
+	Nome       string // Nome no cabeçalho (vazio: tipo, algoritmo e semente)
+	Atalhos    int    // Paredes do labirinto derrubadas para criar caminhos alternativos
+	Vegetacao  int    // Manchas de vegetação
+	Inimigos   int
+	Spawns     int
+	Guardas    int
+	Portais    int // Pares de portais
+	Armadilhas int
+}
+
+// OpcoesPadrao retorna as opções de um labirinto do tamanho dos mapas do jogo.
+func OpcoesPadrao() Opcoes {
+	return Opcoes{
+		Tipo:       TipoLabirinto,
+		Algoritmo:  AlgoritmoBacktracking,
+		Largura:    80,
+		Altura:     30,
+		Atalhos:    40,
+		Vegetacao:  6,
+		Inimigos:   8,
+		Spawns:     4,
+		Guardas:    1,
+		Portais:    1,
+		Armadilhas: 3,
+	}
+}
+
+// Validar confere as opções antes da geração.
+func (o Opcoes) Validar() error {
+	if !slices.Contains(Tipos, o.Tipo) {
+		return fmt.Errorf("tipo de mapa desconhecido %q (use %s)", o.Tipo, strings.Join(Tipos, ", "))
+	}
+	if o.Tipo == TipoLabirinto && !slices.Contains(Algoritmos, o.Algoritmo) {
+		return fmt.Errorf("algoritmo desconhecido %q (use %s)", o.Algoritmo, strings.Join(Algoritmos, ", "))
+	}
+	if o.Largura < tamanhoMinimo || o.Altura < tamanhoMinimo {
+		return fmt.Errorf("tamanho %dx%d pequeno demais (mínimo %dx%d)", o.Largura, o.Altura, tamanhoMinimo, tamanhoMinimo)
+	}
+	if o.Spawns < 1 {
+		return fmt.Errorf("o mapa precisa de pelo menos um spawn")
+	}
+	if o.Atalhos < 0 || o.Vegetacao < 0 || o.Inimigos < 0 || o.Guardas < 0 || o.Portais < 0 || o.Armadilhas < 0 {
+		return fmt.Errorf("quantidades não podem ser negativas")
+	}
+	return nil
+}
+
+// Gerar cria o mapa descrito pelas opções e retorna as linhas do arquivo:
+// o cabeçalho, com os spawns e as entidades, e a grade.
+func Gerar(o Opcoes) ([]string, error) {
+	if err := o.Validar(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(o.Semente))
+	g := novaGrade(o.Largura, o.Altura, simboloParede)
+	switch o.Tipo {
+	case TipoLabirinto:
+		gerarLabirinto(g, o.Algoritmo, rng)
+		derrubarParedes(g, o.Atalhos, rng)
+	case TipoMasmorra:
+		gerarMasmorra(g, rng)
+	}
+	// Spawns e entidades ocupam células vazias distintas, sorteadas antes da vegetação
+	// e dos inimigos para que sempre haja lugar para eles
+	usadas := make(map[posicao]bool)
+	sortear := func(n int) []posicao {
+		var ps []posicao
+		for len(ps) < n {
+			p, ok := g.sortearVazia(usadas, rng)
+			if !ok {
+				break
+			}
+			usadas[p] = true
+			ps = append(ps, p)
+		}
+		return ps
+	}
+	spawns := sortear(o.Spawns)
+	if len(spawns) == 0 {
+		return nil, fmt.Errorf("não há espaço livre para os spawns")
+	}
+	guardas := sortear(o.Guardas)
+	portais := sortear(2 * o.Portais)
+	armadilhas := sortear(o.Armadilhas)
+	for i := 0; i < o.Vegetacao; i++ {
+		plantarVegetacao(g, rng)
+	}
+	for i := 0; i < o.Inimigos; i++ {
+		posicionarInimigo(g, usadas, rng)
+	}
+
+	nome := o.Nome
+	if nome == "" {
+		nome = o.descricao()
+	}
+	linhas := []string{
+		"---",
+		fmt.Sprintf("# Gerado: %s, semente %d", o.descricao(), o.Semente),
+		"nome: " + nome,
+		"autor: gerador",
+		fmt.Sprintf("tamanho: %dx%d", o.Largura, o.Altura),
+		"spawn: " + listaPosicoes(spawns),
+	}
+	if len(guardas) > 0 {
+		linhas = append(linhas, "guarda: "+listaPosicoes(guardas))
+	}
+	for i := 0; i+1 < len(portais); i += 2 {
+		linhas = append(linhas, "portal: "+listaPosicoes(portais[i:i+2]))
+	}
+	if len(armadilhas) > 0 {
+		linhas = append(linhas, "armadilha: "+listaPosicoes(armadilhas))
+	}
+	linhas = append(linhas, "---")
+	for _, linha := range g {
+		linhas = append(linhas, string(linha))
+	}
+	return linhas, nil
+}
+
+// descricao resume o tipo e o algoritmo do mapa: "labirinto (prim)", "masmorra".
+func (o Opcoes) descricao() string {
+	if o.Tipo == TipoLabirinto {
+		return fmt.Sprintf("%s (%s)", o.Tipo, o.Algoritmo)
+	}
+	return o.Tipo
+}
+
+// posicao é uma célula da grade.
+type posicao struct{ x, y int }
+
+// Deslocamentos dos quatro movimentos do jogo
+var direcoes = []posicao{{0, -1}, {-1, 0}, {0, 1}, {1, 0}}
+
+// grade é o mapa em construção, uma linha de símbolos por linha do arquivo.
+type grade [][]rune
+
+func novaGrade(largura, altura int, simbolo rune) grade {
+	g := make(grade, altura)
+	for y := range g {
+		g[y] = make([]rune, largura)
+		for x := range g[y] {
+			g[y][x] = simbolo
+		}
+	}
+	return g
+}
+
+func (g grade) dentro(p posicao) bool {
+	return p.y >= 0 && p.y < len(g) && p.x >= 0 && p.x < len(g[p.y])
+}
+
+// caminhavel indica se o jogador pode entrar na célula (vazio ou vegetação).
+func (g grade) caminhavel(p posicao) bool {
+	return g.dentro(p) && (g[p.y][p.x] == simboloVazio || g[p.y][p.x] == simboloVegetacao)
+}
+
+// sortearVazia sorteia uma célula vazia fora de usadas.
+func (g grade) sortearVazia(usadas map[posicao]bool, rng *rand.Rand) (posicao, bool) {
+	var livres []posicao
+	for y, linha := range g {
+		for x, s := range linha {
+			if p := (posicao{x, y}); s == simboloVazio && !usadas[p] {
+				livres = append(livres, p)
+			}
+		}
+	}
+	if len(livres) == 0 {
+		return posicao{}, false
+	}
+	return livres[rng.Intn(len(livres))], true
+}
+
+// plantarVegetacao espalha uma mancha de vegetação a partir de uma célula vazia,
+// num passeio aleatório pelas células caminháveis. Vegetação não bloqueia a passagem,
+// e pode cobrir spawns e entidades.
+func plantarVegetacao(g grade, rng *rand.Rand) {
+	p, ok := g.sortearVazia(nil, rng)
+	if !ok {
+		return
+	}
+	for passos := 4 + rng.Intn(12); passos > 0; passos-- {
+		g[p.y][p.x] = simboloVegetacao
+		d := direcoes[rng.Intn(len(direcoes))]
+		if proxima := (posicao{p.x + d.x, p.y + d.y}); g.caminhavel(proxima) {
+			p = proxima
+		}
+	}
+}
+
+// posicionarInimigo põe um inimigo numa célula vazia, fora de usadas, cuja ocupação não
+// separa o mapa: a ponta de um beco (uma única vizinha caminhável) ou uma célula cercada
+// de células caminháveis nas oito direções.
+func posicionarInimigo(g grade, usadas map[posicao]bool, rng *rand.Rand) {
+	var candidatas []posicao
+	for y, linha := range g {
+		for x, s := range linha {
+			if p := (posicao{x, y}); s == simboloVazio && !usadas[p] && g.semCortar(p) {
+				candidatas = append(candidatas, p)
+			}
+		}
+	}
+	if len(candidatas) == 0 {
+		return
+	}
+	p := candidatas[rng.Intn(len(candidatas))]
+	g[p.y][p.x] = simboloInimigo
+}
+
+// semCortar indica se bloquear p mantém conexas as demais células caminháveis.
+func (g grade) semCortar(p posicao) bool {
+	vizinhas := 0
+	for _, d := range direcoes {
+		if g.caminhavel(posicao{p.x + d.x, p.y + d.y}) {
+			vizinhas++
+		}
+	}
+	if vizinhas == 1 {
+		return true
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if (dx != 0 || dy != 0) && !g.caminhavel(posicao{p.x + dx, p.y + dy}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// listaPosicoes formata posições como no cabeçalho do mapa: "x,y x,y".
+func listaPosicoes(ps []posicao) string {
+	partes := make([]string, len(ps))
+	for i, p := range ps {
+		partes[i] = fmt.Sprintf("%d,%d", p.x, p.y)
+	}
+	return strings.Join(partes, " ")
+}
@@ -0,0 +1,160 @@
+package gerador
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// opcoesTeste retorna as opções padrão para o tipo e o algoritmo informados.
+func opcoesTeste(tipo, algoritmo string, largura, altura int, semente int64) Opcoes {
+	o := OpcoesPadrao()
+	o.Tipo, o.Algoritmo = tipo, algoritmo
+	o.Largura, o.Altura = largura, altura
+	o.Semente = semente
+	return o
+}
+
+// variantes lista todas as combinações de tipo e algoritmo.
+func variantes() [][2]string {
+	var v [][2]string
+	for _, algoritmo := range Algoritmos {
+		v = append(v, [2]string{TipoLabirinto, algoritmo})
+	}
+	return append(v, [2]string{TipoMasmorra, ""})
+}
+
+// lerMapaTeste separa as linhas geradas na grade e nas posições declaradas no cabeçalho.
+func lerMapaTeste(t *testing.T, linhas []string) (grade, []posicao) {
+	t.Helper()
+	if len(linhas) == 0 || linhas[0] != "---" {
+		t.Fatalf("mapa sem cabeçalho: %q", linhas)
+	}
+	fim := slices.Index(linhas[1:], "---") + 1
+	if fim == 0 {
+		t.Fatal("cabeçalho sem fechamento")
+	}
+	var declaradas []posicao
+	for _, linha := range linhas[1:fim] {
+		chave, valor, _ := strings.Cut(linha, ": ")
+		if chave != "spawn" && chave != "guarda" && chave != "portal" && chave != "armadilha" {
+			continue
+		}
+		for _, campo := range strings.Fields(valor) {
+			var p posicao
+			if _, err := fmt.Sscanf(campo, "%d,%d", &p.x, &p.y); err != nil {
+				t.Fatalf("posição inválida %q na linha %q", campo, linha)
+			}
+			declaradas = append(declaradas, p)
+		}
+	}
+	var g grade
+	for _, linha := range linhas[fim+1:] {
+		g = append(g, []rune(linha))
+	}
+	return g, declaradas
+}
+
+func TestGerarDeterminista(t *testing.T) {
+	for _, v := range variantes() {
+		t.Run(strings.Trim(v[0]+"-"+v[1], "-"), func(t *testing.T) {
+			o := opcoesTeste(v[0], v[1], 40, 20, 42)
+			a, errA := Gerar(o)
+			b, errB := Gerar(o)
+			if errA != nil || errB != nil {
+				t.Fatalf("Gerar: %v, %v", errA, errB)
+			}
+			if !slices.Equal(a, b) {
+				t.Error("a mesma semente gerou mapas diferentes")
+			}
+			o.Semente++
+			if c, _ := Gerar(o); slices.Equal(a, c) {
+				t.Error("sementes diferentes geraram o mesmo mapa")
+			}
+		})
+	}
+}
+
+func TestGerarConexo(t *testing.T) {
+	tamanhos := [][2]int{{80, 30}, {tamanhoMinimo, tamanhoMinimo}, {20, 11}, {21, 12}}
+	for _, v := range variantes() {
+		for _, tamanho := range tamanhos {
+			for semente := int64(1); semente <= 5; semente++ {
+				nome := fmt.Sprintf("%s %s %dx%d semente %d", v[0], v[1], tamanho[0], tamanho[1], semente)
+				t.Run(nome, func(t *testing.T) {
+					linhas, err := Gerar(opcoesTeste(v[0], v[1], tamanho[0], tamanho[1], semente))
+					if err != nil {
+						t.Fatalf("Gerar: %v", err)
+					}
+					g, declaradas := lerMapaTeste(t, linhas)
+					if len(g) != tamanho[1] {
+						t.Fatalf("altura %d, esperada %d", len(g), tamanho[1])
+					}
+					for y, linha := range g {
+						if len(linha) != tamanho[0] {
+							t.Fatalf("linha %d com largura %d, esperada %d", y, len(linha), tamanho[0])
+						}
+					}
+					if len(declaradas) == 0 {
+						t.Fatal("nenhum spawn declarado")
+					}
+
+					// Busca em largura a partir do primeiro spawn, sobre as células caminháveis
+					alcancadas := map[posicao]bool{declaradas[0]: true}
+					fila := []posicao{declaradas[0]}
+					for len(fila) > 0 {
+						p := fila[0]
+						fila = fila[1:]
+						for _, d := range direcoes {
+							q := posicao{p.x + d.x, p.y + d.y}
+							if g.caminhavel(q) && !alcancadas[q] {
+								alcancadas[q] = true
+								fila = append(fila, q)
+							}
+						}
+					}
+					for y, linha := range g {
+						for x := range linha {
+							if p := (posicao{x, y}); g.caminhavel(p) && !alcancadas[p] {
+								t.Fatalf("célula (%d, %d) inalcançável a partir do spawn %v", x, y, declaradas[0])
+							}
+						}
+					}
+					usadas := make(map[posicao]bool)
+					for _, p := range declaradas {
+						if !g.caminhavel(p) || usadas[p] {
+							t.Errorf("posição declarada %v bloqueada ou repetida", p)
+						}
+						usadas[p] = true
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOpcoesValidar(t *testing.T) {
+	casos := []struct {
+		nome   string
+		altera func(*Opcoes)
+		valida bool
+	}{
+		{"padrão", func(*Opcoes) {}, true},
+		{"masmorra ignora o algoritmo", func(o *Opcoes) { o.Tipo, o.Algoritmo = TipoMasmorra, "" }, true},
+		{"tipo desconhecido", func(o *Opcoes) { o.Tipo = "caverna" }, false},
+		{"algoritmo desconhecido", func(o *Opcoes) { o.Algoritmo = "eller" }, false},
+		{"pequeno demais", func(o *Opcoes) { o.Largura = tamanhoMinimo - 1 }, false},
+		{"sem spawn", func(o *Opcoes) { o.Spawns = 0 }, false},
+		{"quantidade negativa", func(o *Opcoes) { o.Inimigos = -1 }, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			o := OpcoesPadrao()
+			c.altera(&o)
+			if err := o.Validar(); (err == nil) != c.valida {
+				t.Errorf("Validar() = %v, esperado válido: %v", err, c.valida)
+			}
+		})
+	}
+}
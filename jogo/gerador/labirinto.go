@@ -0,0 +1,221 @@
+// labirinto.go - Labirintos perfeitos por busca em profundidade, Prim, Kruskal e divisão recursiva
+// As células do labirinto ficam nas coordenadas ímpares da grade; entre duas células
+// vizinhas há uma parede, derrubada quando o algoritmo as liga. Sem atalhos
+// (derrubarParedes), existe exatamente um caminho entre duas células quaisquer.
+package gerador
+
+import "math/rand"
+
+// celula é uma célula do labirinto; na grade, fica em (2x+1, 2y+1).
+type celula struct{ x, y int }
+
+func (c celula) naGrade() posicao { return posicao{2*c.x + 1, 2*c.y + 1} }
+
+// labirinto descreve as células disponíveis numa grade.
+type labirinto struct {
+	g               grade
+	colunas, linhas int
+}
+
+// gerarLabirinto escava na grade, toda de paredes, um labirinto pelo algoritmo informado.
+func gerarLabirinto(g grade, algoritmo string, rng *rand.Rand) {
+	l := labirinto{g: g, colunas: (len(g[0]) - 1) / 2, linhas: (len(g) - 1) / 2}
+	switch algoritmo {
+	case AlgoritmoBacktracking:
+		l.backtracking(rng)
+	case AlgoritmoPrim:
+		l.prim(rng)
+	case AlgoritmoKruskal:
+		l.kruskal(rng)
+	case AlgoritmoDivisao:
+		l.divisao(rng)
+	}
+}
+
+func (l labirinto) valida(c celula) bool {
+	return c.x >= 0 && c.x < l.colunas && c.y >= 0 && c.y < l.linhas
+}
+
+// abrir escava a célula c.
+func (l labirinto) abrir(c celula) {
+	p := c.naGrade()
+	l.g[p.y][p.x] = simboloVazio
+}
+
+// ligar escava as células a e b, vizinhas, e a parede entre elas.
+func (l labirinto) ligar(a, b celula) {
+	l.abrir(a)
+	l.abrir(b)
+	pa, pb := a.naGrade(), b.naGrade()
+	l.g[(pa.y+pb.y)/2][(pa.x+pb.x)/2] = simboloVazio
+}
+
+// vizinhas retorna as células vizinhas de c dentro do labirinto.
+func (l labirinto) vizinhas(c celula) []celula {
+	var vs []celula
+	for _, d := range direcoes {
+		if v := (celula{c.x + d.x, c.y + d.y}); l.valida(v) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// backtracking percorre o labirinto em profundidade a partir de uma célula sorteada,
+// voltando pela pilha quando não há vizinha nova.
+func (l labirinto) backtracking(rng *rand.Rand) {
+	visitadas := make(map[celula]bool)
+	inicio := celula{rng.Intn(l.colunas), rng.Intn(l.linhas)}
+	l.abrir(inicio)
+	visitadas[inicio] = true
+	pilha := []celula{inicio}
+	for len(pilha) > 0 {
+		atual := pilha[len(pilha)-1]
+		var novas []celula
+		for _, v := range l.vizinhas(atual) {
+			if !visitadas[v] {
+				novas = append(novas, v)
+			}
+		}
+		if len(novas) == 0 {
+			pilha = pilha[:len(pilha)-1]
+			continue
+		}
+		proxima := novas[rng.Intn(len(novas))]
+		l.ligar(atual, proxima)
+		visitadas[proxima] = true
+		pilha = append(pilha, proxima)
+	}
+}
+
+// prim cresce o labirinto a partir de uma célula, ligando a cada passo uma célula
+// sorteada da fronteira a uma vizinha já incluída.
+func (l labirinto) prim(rng *rand.Rand) {
+	incluidas := make(map[celula]bool)
+	naFronteira := make(map[celula]bool)
+	var fronteira []celula
+	incluir := func(c celula) {
+		incluidas[c] = true
+		l.abrir(c)
+		for _, v := range l.vizinhas(c) {
+			if !incluidas[v] && !naFronteira[v] {
+				naFronteira[v] = true
+				fronteira = append(fronteira, v)
+			}
+		}
+	}
+	incluir(celula{rng.Intn(l.colunas), rng.Intn(l.linhas)})
+	for len(fronteira) > 0 {
+		i := rng.Intn(len(fronteira))
+		c := fronteira[i]
+		fronteira[i] = fronteira[len(fronteira)-1]
+		fronteira = fronteira[:len(fronteira)-1]
+
+		var ligadas []celula
+		for _, v := range l.vizinhas(c) {
+			if incluidas[v] {
+				ligadas = append(ligadas, v)
+			}
+		}
+		l.ligar(ligadas[rng.Intn(len(ligadas))], c)
+		incluir(c)
+	}
+}
+
+// kruskal percorre as paredes entre células em ordem aleatória e derruba cada uma
+// que separa dois conjuntos de células ainda desconectados.
+func (l labirinto) kruskal(rng *rand.Rand) {
+	pai := make(map[celula]celula)
+	var raiz func(c celula) celula
+	raiz = func(c celula) celula {
+		p, ok := pai[c]
+		if !ok || p == c {
+			return c
+		}
+		r := raiz(p)
+		pai[c] = r
+		return r
+	}
+
+	type aresta struct{ a, b celula }
+	var arestas []aresta
+	for y := 0; y < l.linhas; y++ {
+		for x := 0; x < l.colunas; x++ {
+			c := celula{x, y}
+			l.abrir(c)
+			if x+1 < l.colunas {
+				arestas = append(arestas, aresta{c, celula{x + 1, y}})
+			}
+			if y+1 < l.linhas {
+				arestas = append(arestas, aresta{c, celula{x, y + 1}})
+			}
+		}
+	}
+	rng.Shuffle(len(arestas), func(i, j int) { arestas[i], arestas[j] = arestas[j], arestas[i] })
+	for _, e := range arestas {
+		if ra, rb := raiz(e.a), raiz(e.b); ra != rb {
+			pai[ra] = rb
+			l.ligar(e.a, e.b)
+		}
+	}
+}
+
+// divisao abre toda a área do labirinto e a divide recursivamente com paredes,
+// deixando uma passagem em cada uma.
+func (l labirinto) divisao(rng *rand.Rand) {
+	for y := 1; y < 2*l.linhas; y++ {
+		for x := 1; x < 2*l.colunas; x++ {
+			l.g[y][x] = simboloVazio
+		}
+	}
+	l.dividir(0, 0, l.colunas, l.linhas, rng)
+}
+
+// dividir separa a região de largura x altura células a partir de (x0, y0) com uma
+// parede na direção da maior dimensão e trata as duas metades.
+func (l labirinto) dividir(x0, y0, largura, altura int, rng *rand.Rand) {
+	if largura < 2 || altura < 2 {
+		return
+	}
+	horizontal := altura > largura || (altura == largura && rng.Intn(2) == 0)
+	if horizontal {
+		k := 1 + rng.Intn(altura-1)
+		passagem := x0 + rng.Intn(largura)
+		y := 2 * (y0 + k)
+		for x := 2*x0 + 1; x < 2*(x0+largura); x++ {
+			l.g[y][x] = simboloParede
+		}
+		l.g[y][2*passagem+1] = simboloVazio
+		l.dividir(x0, y0, largura, k, rng)
+		l.dividir(x0, y0+k, largura, altura-k, rng)
+		return
+	}
+	k := 1 + rng.Intn(largura-1)
+	passagem := y0 + rng.Intn(altura)
+	x := 2 * (x0 + k)
+	for y := 2*y0 + 1; y < 2*(y0+altura); y++ {
+		l.g[y][x] = simboloParede
+	}
+	l.g[2*passagem+1][x] = simboloVazio
+	l.dividir(x0, y0, k, altura, rng)
+	l.dividir(x0+k, y0, largura-k, altura, rng)
+}
+
+// derrubarParedes abre até n paredes internas entre duas células caminháveis,
+// criando caminhos alternativos no labirinto.
+func derrubarParedes(g grade, n int, rng *rand.Rand) {
+	for tentativas := 0; n > 0 && tentativas < 50*n; tentativas++ {
+		p := posicao{1 + rng.Intn(len(g[0])-2), 1 + rng.Intn(len(g)-2)}
+		if g[p.y][p.x] != simboloParede {
+			continue
+		}
+		horizontal := g.caminhavel(posicao{p.x - 1, p.y}) && g.caminhavel(posicao{p.x + 1, p.y}) &&
+			!g.caminhavel(posicao{p.x, p.y - 1}) && !g.caminhavel(posicao{p.x, p.y + 1})
+		vertical := g.caminhavel(posicao{p.x, p.y - 1}) && g.caminhavel(posicao{p.x, p.y + 1}) &&
+			!g.caminhavel(posicao{p.x - 1, p.y}) && !g.caminhavel(posicao{p.x + 1, p.y})
+		if horizontal || vertical {
+			g[p.y][p.x] = simboloVazio
+			n--
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// masmorra.go - Masmorras de salas retangulares ligadas por corredores
+// As salas são sorteadas sem se sobrepor (com pelo menos uma parede entre elas) e
+// ligadas, da esquerda para a direita, por corredores em L, de modo que todas se
+// alcançam.
+package gerador
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Limites do tamanho das salas, em células livres
+const (
+	larguraSalaMinima = 3
+	larguraSalaMaxima = 12
+	alturaSalaMinima  = 3
+	alturaSalaMaxima  = 7
+)
+
+// Área da grade, em células, para cada sala pretendida
+const areaPorSala = 180
+
+// retangulo é uma sala da masmorra.
+type retangulo struct{ x, y, largura, altura int }
+
+// centro retorna a célula central da sala.
+func (r retangulo) centro() posicao {
+	return posicao{r.x + r.largura/2, r.y + r.altura/2}
+}
+
+// separado indica se r e outro ficam a pelo menos uma parede de distância.
+func (r retangulo) separado(outro retangulo) bool {
+	return r.x+r.largura < outro.x || outro.x+outro.largura < r.x ||
+		r.y+r.altura < outro.y || outro.y+outro.altura < r.y
+}
+
+// gerarMasmorra escava salas e corredores na grade, toda de paredes.
+func gerarMasmorra(g grade, rng *rand.Rand) {
+	largura, altura := len(g[0]), len(g)
+	maxLargura := min(larguraSalaMaxima, largura-2)
+	maxAltura := min(alturaSalaMaxima, altura-2)
+	alvo := max(2, largura*altura/areaPorSala)
+
+	var salas []retangulo
+	for tentativas := 0; tentativas < 30*alvo && len(salas) < alvo; tentativas++ {
+		r := retangulo{
+			largura: larguraSalaMinima + rng.Intn(maxLargura-larguraSalaMinima+1),
+			altura:  alturaSalaMinima + rng.Intn(maxAltura-alturaSalaMinima+1),
+		}
+		r.x = 1 + rng.Intn(largura-1-r.largura)
+		r.y = 1 + rng.Intn(altura-1-r.altura)
+		separada := true
+		for _, s := range salas {
+			if !r.separado(s) {
+				separada = false
+				break
+			}
+		}
+		if !separada {
+			continue
+		}
+		for y := r.y; y < r.y+r.altura; y++ {
+			for x := r.x; x < r.x+r.largura; x++ {
+				g[y][x] = simboloVazio
+			}
+		}
+		salas = append(salas, r)
+	}
+
+	sort.Slice(salas, func(i, j int) bool { return salas[i].centro().x < salas[j].centro().x })
+	for i := 1; i < len(salas); i++ {
+		escavarCorredor(g, salas[i-1].centro(), salas[i].centro(), rng.Intn(2) == 0)
+	}
+}
+
+// escavarCorredor liga a e b por um corredor em L, primeiro na horizontal ou na vertical.
+func escavarCorredor(g grade, a, b posicao, horizontalPrimeiro bool) {
+	canto := posicao{b.x, a.y}
+	if !horizontalPrimeiro {
+		canto = posicao{a.x, b.y}
+	}
+	escavarReta(g, a, canto)
+	escavarReta(g, canto, b)
+}
+
+// escavarReta abre as células de a até b, na mesma linha ou coluna.
+func escavarReta(g grade, a, b posicao) {
+	dx, dy := sinal(b.x-a.x), sinal(b.y-a.y)
+	for p := a; ; p = (posicao{p.x + dx, p.y + dy}) {
+		g[p.y][p.x] = simboloVazio
+		if p == b {
+			return
+		}
+	}
+}
+
+func sinal(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
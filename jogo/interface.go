@@ -6,6 +6,9 @@
 package main
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/nsf/termbox-go"
 )
 
@@ -24,12 +27,13 @@ const (
 	CorFundoParede    = termbox.ColorDarkGray
 	CorTexto          = termbox.ColorDarkGray
 	CorAzul		   	  = termbox.ColorBlue
+	CorDestaque       = termbox.ColorDefault | termbox.AttrBold
 )
 
 // EventoTeclado representa uma ação detectada do teclado (como mover, sair ou interagir)
 type EventoTeclado struct {
-	Tipo  string // "sair", "interagir", "mover"
-	Tecla rune   // Tecla pressionada, usada no caso de movimento
+	Tipo  string // "sair", "interagir", "mover", "pronto", "chat", "camera", "proximaSala" e os do editor (interfaceLerEventoEditor)
+	Tecla rune   // Tecla pressionada, usada no caso de movimento, de câmera e de cursor (w, a, s, d) e de pincel (1 a 8)
 }
 
 // Inicializa a interface gráfica usando termbox
@@ -56,22 +60,124 @@ func interfaceLerEventoTeclado() EventoTeclado {
 	if ev.Ch == 'e' {
 		return EventoTeclado{Tipo: "interagir"}
 	}
+	if ev.Ch == 't' {
+		return EventoTeclado{Tipo: "chat"}
+	}
+	if ev.Key == termbox.KeyEnter {
+		return EventoTeclado{Tipo: "pronto"}
+	}
+	// Setas e TAB só são usadas pelo espectador (espectador.go)
+	switch ev.Key {
+	case termbox.KeyArrowUp:
+		return EventoTeclado{Tipo: "camera", Tecla: 'w'}
+	case termbox.KeyArrowLeft:
+		return EventoTeclado{Tipo: "camera", Tecla: 'a'}
+	case termbox.KeyArrowDown:
+		return EventoTeclado{Tipo: "camera", Tecla: 's'}
+	case termbox.KeyArrowRight:
+		return EventoTeclado{Tipo: "camera", Tecla: 'd'}
+	case termbox.KeyTab:
+		return EventoTeclado{Tipo: "proximaSala"}
+	}
 	return EventoTeclado{Tipo: "mover", Tecla: ev.Ch}
 }
 
+// interfaceLerEventoEditor lê um evento do teclado no editor de mapas (editor.go):
+// "cursor" (WASD ou setas), "pincel" (1 a 8), "pintar" (espaço ou ENTER), "apagar" (X ou DEL),
+// "tracar" (P), "desfazer" (U ou Ctrl+Z), "refazer" (R ou Ctrl+Y), "validar" (V),
+// "salvar" (G ou Ctrl+S) e "sair" (ESC).
+func interfaceLerEventoEditor() EventoTeclado {
+	ev := termbox.PollEvent()
+	if ev.Type != termbox.EventKey {
+		return EventoTeclado{}
+	}
+	switch ev.Key {
+	case termbox.KeyEsc:
+		return EventoTeclado{Tipo: "sair"}
+	case termbox.KeyArrowUp:
+		return EventoTeclado{Tipo: "cursor", Tecla: 'w'}
+	case termbox.KeyArrowLeft:
+		return EventoTeclado{Tipo: "cursor", Tecla: 'a'}
+	case termbox.KeyArrowDown:
+		return EventoTeclado{Tipo: "cursor", Tecla: 's'}
+	case termbox.KeyArrowRight:
+		return EventoTeclado{Tipo: "cursor", Tecla: 'd'}
+	case termbox.KeySpace, termbox.KeyEnter:
+		return EventoTeclado{Tipo: "pintar"}
+	case termbox.KeyDelete:
+		return EventoTeclado{Tipo: "apagar"}
+	case termbox.KeyCtrlZ:
+		return EventoTeclado{Tipo: "desfazer"}
+	case termbox.KeyCtrlY:
+		return EventoTeclado{Tipo: "refazer"}
+	case termbox.KeyCtrlS:
+		return EventoTeclado{Tipo: "salvar"}
+	}
+	switch ev.Ch {
+	case 'w', 'a', 's', 'd':
+		return EventoTeclado{Tipo: "cursor", Tecla: ev.Ch}
+	case '1', '2', '3', '4', '5', '6', '7', '8':
+		return EventoTeclado{Tipo: "pincel", Tecla: ev.Ch}
+	case 'x':
+		return EventoTeclado{Tipo: "apagar"}
+	case 'p':
+		return EventoTeclado{Tipo: "tracar"}
+	case 'u':
+		return EventoTeclado{Tipo: "desfazer"}
+	case 'r':
+		return EventoTeclado{Tipo: "refazer"}
+	case 'v':
+		return EventoTeclado{Tipo: "validar"}
+	case 'g':
+		return EventoTeclado{Tipo: "salvar"}
+	}
+	return EventoTeclado{}
+}
+
+// interfaceLerTexto lê uma linha digitada pelo jogador, exibida na barra de status
+// após o prompt. ENTER confirma e ESC cancela (retorna false).
+func interfaceLerTexto(jogo *Jogo, prompt string) (string, bool) {
+	var texto []rune
+	defer withMapaLock(func() {
+		jogo.Entrada = ""
+		interfaceDesenharJogo(jogo)
+	})
+	for {
+		withMapaLock(func() {
+			jogo.Entrada = prompt + string(texto)
+			interfaceDesenharJogo(jogo)
+		})
+		ev := termbox.PollEvent()
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+		switch {
+		case ev.Key == termbox.KeyEsc:
+			return "", false
+		case ev.Key == termbox.KeyEnter:
+			return string(texto), true
+		case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+			if len(texto) > 0 {
+				texto = texto[:len(texto)-1]
+			}
+		case ev.Key == termbox.KeySpace:
+			texto = append(texto, ' ')
+		case ev.Ch != 0:
+			texto = append(texto, ev.Ch)
+		}
+	}
+}
+
 // Renderiza todo o estado atual do jogo na tela
 func interfaceDesenharJogo(jogo *Jogo) {
 	interfaceLimparTela()
 
-	// Desenha todos os elementos do mapa
-	for y, linha := range jogo.Mapa {
-		for x, elem := range linha {
-			interfaceDesenharElemento(x, y, elem)
-		}
-	}
+	// Desenha o mapa, deslocado pela câmera do espectador; o personagem está na camada
+	// de entidades, junto com os demais jogadores e o guarda
+	interfaceDesenharCamadas(&jogo.Mapa, jogo.CameraX, jogo.CameraY)
 
-	// Desenha o personagem sobre o mapa
-	interfaceDesenharElemento(jogo.PosX, jogo.PosY, Personagem)
+	// Desenha a lista de jogadores à direita do mapa
+	interfaceDesenharJogadores(jogo)
 
 	// Desenha a barra de status
 	interfaceDesenharBarraDeStatus(jogo)
@@ -95,17 +201,100 @@ func interfaceDesenharElemento(x, y int, elem Elemento) {
 	termbox.SetCell(x, y, elem.simbolo, elem.cor, elem.corFundo)
 }
 
+// Desenha o terreno e, por cima, o elemento visível de cada camada (camadas.go),
+// deslocados pela câmera
+func interfaceDesenharCamadas(mapa *MapaCamadas, cameraX, cameraY int) {
+	for y, linha := range mapa.Terreno {
+		for x, elem := range linha {
+			interfaceDesenharElemento(x-cameraX, y-cameraY, elem)
+		}
+	}
+	for p, elem := range mapa.compor() {
+		interfaceDesenharElemento(p.X-cameraX, p.Y-cameraY, elem)
+	}
+}
+
+// Retorna o elemento com as cores invertidas, para destacá-lo (cursor do editor)
+func interfaceRealcar(elem Elemento) Elemento {
+	elem.cor |= termbox.AttrReverse
+	return elem
+}
+
+// Retorna a largura e a altura do terminal, em células
+func interfaceTamanhoTela() (int, int) {
+	return termbox.Size()
+}
+
 // Exibe uma barra de status com informações úteis ao jogador
 func interfaceDesenharBarraDeStatus(jogo *Jogo) {
+	base := len(jogo.Mapa.Terreno) - jogo.CameraY
+
 	// Linha de status dinâmica
 	for i, c := range jogo.StatusMsg {
-		termbox.SetCell(i, len(jogo.Mapa)+1, c, CorTexto, CorPadrao)
+		termbox.SetCell(i, base+1, c, CorTexto, CorPadrao)
+	}
+
+	// Estado da conexão com o servidor
+	for i, c := range jogo.Conexao {
+		termbox.SetCell(i, base+2, c, CorTexto, CorPadrao)
 	}
 
-	// Instruções fixas
-	msg := "Use WASD para mover e E para interagir. ESC para sair."
+	// Instruções fixas (na sala de espera, como ficar pronto); durante o chat, o texto digitado
+	msg := "Use WASD para mover, E para interagir e T para conversar. ESC para sair."
+	if jogo.Servidor.Fase == FaseEspera {
+		msg = "Sala de espera: ENTER para ficar pronto (ou deixar de estar), T para conversar. ESC para sair."
+		if jogo.Servidor.Contagem > 0 {
+			msg += fmt.Sprintf(" Início em %ds.", jogo.Servidor.Contagem)
+		}
+	}
+	if jogo.Espectador {
+		msg = "Espectador: WASD ou setas movem a câmera, TAB troca de sala. ESC para sair."
+	}
+	if jogo.Entrada != "" {
+		msg = jogo.Entrada
+	}
 	for i, c := range msg {
-		termbox.SetCell(i, len(jogo.Mapa)+3, c, CorTexto, CorPadrao)
+		termbox.SetCell(i, base+3, c, CorTexto, CorPadrao)
+	}
+}
+
+
+// Exibe, à direita do mapa, os nomes e as vidas dos jogadores conectados
+// (na sala de espera, quem já está pronto). O jogador local aparece destacado.
+func interfaceDesenharJogadores(jogo *Jogo) {
+	coluna := jogo.Mapa.largura() + 2 - jogo.CameraX
+
+	ids := make([]string, 0, len(jogo.Servidor.Jogadores))
+	for id := range jogo.Servidor.Jogadores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return jogo.Servidor.Jogadores[ids[i]].Nome < jogo.Servidor.Jogadores[ids[j]].Nome
+	})
+
+	interfaceEscrever(coluna, 0, "Jogadores (sala "+jogo.Servidor.Sala+")", CorTexto)
+	meuID := clienteJogadorID()
+	for i, id := range ids {
+		jogador := jogo.Servidor.Jogadores[id]
+		cor := CorAzul
+		if id == meuID {
+			cor = CorPadrao | termbox.AttrBold
+		}
+		info := fmt.Sprintf("%d♥", jogador.Vidas)
+		if jogo.Servidor.Fase == FaseEspera {
+			info = "aguardando"
+			if jogador.Pronto {
+				info = "pronto ✓"
+			}
+		}
+		interfaceEscrever(coluna, i+1, fmt.Sprintf("%-16s %s", jogador.Nome, info), cor)
 	}
 }
 
+// Escreve um texto a partir da posição (x, y)
+func interfaceEscrever(x, y int, texto string, cor Cor) {
+	for _, c := range texto {
+		termbox.SetCell(x, y, c, cor, CorPadrao)
+		x++
+	}
+}
@@ -3,10 +3,13 @@ package main
 
 import (
     "bufio"
-    "math/rand"
+    "encoding/gob"
     "fmt"
+    "math/rand"
+    "net"
     "net/rpc"
     "os"
+    "sync/atomic"
     "time"
 )
 
@@ -20,38 +23,26 @@ type Elemento struct {
     tangivel bool
 }
 
-type Guarda struct {
-    Elemento
-    X, Y             int
-    Perseguir        chan bool
-    PararPerseguicao chan bool
-}
-
-type Portal struct {
-    Elemento
-    X, Y                int
-    Teletransportar     chan bool
-    PararTeletransporte chan bool
-    Ativo               bool 
-}
-
-type Armadilha struct {
-    Elemento
-    X, Y               int
-    ProximidadeJogador chan bool
-    ProximidadeOutro   chan bool
-    PararArmadilha     chan bool
+// Posicao é uma coordenada do mapa
+type Posicao struct {
+    X, Y int
 }
 
 // Jogo contém o estado atual do jogo
 type Jogo struct {
-    Mapa           [][]Elemento 
-    MapaStatic     [][]Elemento // 💡 NOVO: Cópia do mapa estático para redesenho
+    Mapa           MapaCamadas // Terreno e, em camadas separadas, itens, entidades e sobreposições (camadas.go)
     PosX, PosY     int          
-    UltimoVisitado Elemento   
     StatusMsg      string      
     GameOver       bool 
     Vidas          int
+    Servidor       EstadoJogo // Cópia local do estado do servidor, reconstruída a partir dos deltas
+    Conexao        string     // Estado da conexão com o servidor, exibido na barra de status
+    Spawns         []Posicao  // Pontos de spawn declarados no mapa (cabeçalho e ☺ da grade)
+    Info           InfoMapa   // Demais declarações do mapa: nome, autor, tamanho e entidades (mapa.go)
+    Entrada        string     // Texto sendo digitado (chat), exibido na barra de status
+    Espectador     bool       // Modo espectador: sem personagem, com câmera livre (espectador.go)
+    CameraX        int        // Deslocamento da câmera do espectador
+    CameraY        int
 }
 
 // ------------------ ELEMENTOS VISUAIS ------------------
@@ -61,26 +52,12 @@ var (
     Parede     = Elemento{'▤', CorParede, CorFundoParede, true}
     Vegetacao  = Elemento{'♣', CorVerde, CorPadrao, false}
     Vazio      = Elemento{' ', CorPadrao, CorPadrao, false}
+    OutroJogador = Elemento{'☺', CorAzul, CorPadrao, true} // Demais jogadores, vistos por quem joga ou assiste
 
-    // Elementos autônomos
-    guarda = &Guarda{
-        Elemento:         Elemento{'G', CorAmarelo, CorPadrao, true},
-        Perseguir:        make(chan bool),
-        PararPerseguicao: make(chan bool),
-    }
-
-    portal = &Portal{
-        Elemento:            Elemento{'P', CorCiano, CorPadrao, false},
-        Teletransportar:     make(chan bool),
-        PararTeletransporte: make(chan bool),
-    }
-
-    armadilha = &Armadilha{
-        Elemento:           Elemento{'A', CorVermelho, CorPadrao, false},
-        ProximidadeJogador: make(chan bool),
-        ProximidadeOutro:   make(chan bool),
-        PararArmadilha:     make(chan bool),
-    }
+    // Elementos autônomos, controlados pelo servidor e apenas desenhados pelo cliente
+    ElementoGuarda    = Elemento{'G', CorAmarelo, CorPadrao, true}
+    ElementoPortal    = Elemento{'P', CorCiano, CorPadrao, false}
+    ElementoArmadilha = Elemento{'A', CorVermelho, CorPadrao, false}
 )
 
 // ------------------ CANAL DE LOCK ------------------
@@ -94,465 +71,326 @@ func withMapaLock(f func()) {
 
 // ---------------- VARIÁVEIS GLOBAIS MULTIPLAYER ------------------
 var (
-    clienteRPC *rpc.Client 
-    clientID   string     
-    sequence   int     
+    clienteRPC  *rpc.Client 
+    clientID    string // Identificador opaco da sessão atribuído pelo servidor no registro
+    tokenSessao string // Token da sessão, enviado em todo comando e na retomada após uma reconexão
+    jogadorID   string // Identificador público do jogador local no estado do servidor
+    nomeJogador string // Nome escolhido pelo jogador, exibido aos demais
+    sequence    int     
+
+    enderecoServidor string      // Endereço usado pelo RPC e pelo stream de estado
+    streamAtivo      atomic.Bool // true enquanto o stream de estado estiver conectado
 )
+
+// Intervalo entre heartbeats (BuscarEstado) enquanto o stream de estado está ativo
+const intervaloHeartbeat = time.Second
 // ------------------ FUNÇÕES DO JOGO ------------------
 
 // Cria e retorna uma nova instância do jogo
 func jogoNovo() Jogo {
-    return Jogo{UltimoVisitado: Vazio}
+    return Jogo{}
 }
 
+// jogoCarregarMapa lê e monta o mapa de um arquivo no formato de mapa.go.
 func jogoCarregarMapa(nome string, jogo *Jogo) error {
-    arq, err := os.Open(nome)
+    linhas, err := jogoLerArquivoMapa(nome)
     if err != nil {
         return err
     }
+    if err := jogoMontarMapa(linhas, jogo); err != nil {
+        return fmt.Errorf("%s: %w", nome, err)
+    }
+    return nil
+}
+
+// jogoLerArquivoMapa lê as linhas de um arquivo de mapa.
+func jogoLerArquivoMapa(nome string) ([]string, error) {
+    arq, err := os.Open(nome)
+    if err != nil {
+        return nil, err
+    }
     defer arq.Close()
 
+    var linhas []string
     scanner := bufio.NewScanner(arq)
-    y := 0
     for scanner.Scan() {
-        linha := scanner.Text()
-        var linhaElems []Elemento
-        for x, ch := range linha {
-            var e Elemento 
-
-            switch ch {
-            case Parede.simbolo:
-                e = Parede
-            case Vegetacao.simbolo:
-                e = Vegetacao
-            case Inimigo.simbolo:
-                e = Inimigo
-            case Personagem.simbolo:
-                jogo.PosX, jogo.PosY = x, y
-                jogo.UltimoVisitado = Vazio
-                e = Vazio
-            case 'P':
-                e = Vazio
-            case 'G':
-                e = Vazio
-            case 'A':
-                e = Vazio
-            case ' ':
-                e = Vazio
-            default:
-                e = Vazio
-            }
-
-            linhaElems = append(linhaElems, e)
-        }
-        jogo.Mapa = append(jogo.Mapa, linhaElems)
-        y++
+        linhas = append(linhas, scanner.Text())
     }
-
-    // Copia estática do mapa base
-    jogo.MapaStatic = make([][]Elemento, len(jogo.Mapa))
-    for i, row := range jogo.Mapa {
-        jogo.MapaStatic[i] = make([]Elemento, len(row))
-        copy(jogo.MapaStatic[i], row)
+    if err := scanner.Err(); err != nil {
+        return nil, err
     }
+    return linhas, nil
+}
 
-    if err := scanner.Err(); err != nil {
+// jogoMontarMapa monta o mapa do jogo a partir das linhas do arquivo (cabeçalho e grade,
+// ver mapa.go), substituindo o terreno atual; as demais camadas seguem o estado do servidor.
+// Se o mapa tiver problemas, o atual é mantido e todos eles são retornados em ErrosMapa.
+func jogoMontarMapa(linhas []string, jogo *Jogo) error {
+    m, err := mapaInterpretar(linhas)
+    if err != nil {
         return err
     }
-
+    jogo.Mapa.Terreno = m.terreno
+    jogo.Spawns = m.spawns
+    jogo.Info = m.info
+    if len(m.spawns) > 0 {
+        jogo.PosX, jogo.PosY = m.spawns[0].X, m.spawns[0].Y
+    }
     return nil
 }
 
 
-// Verifica se o personagem pode se mover para a posição (x, y)
+// Verifica se o terreno permite entrar na posição (x, y)
 func jogoPodeMoverPara(jogo *Jogo, x, y int) bool {
-    if y < 0 || y >= len(jogo.Mapa) {
-        return false
-    }
-    if x < 0 || x >= len(jogo.Mapa[y]) {
+    if !jogo.Mapa.dentro(x, y) {
         return false
     }
-    if jogo.Mapa[y][x].tangivel {
+    if jogo.Mapa.Terreno[y][x].tangivel {
         return false
     }
     return true
 }
 
-// Move um elemento para a nova posição
-func jogoMoverElemento(jogo *Jogo, x, y, dx, dy int) {
-    nx, ny := x+dx, y+dy
-    if ny < 0 || ny >= len(jogo.Mapa) || nx < 0 || nx >= len(jogo.Mapa[ny]) {
-        return
-    }
-    if jogo.Mapa[ny][nx].tangivel {
-        return
-    }
-    jogoTrocar(jogo, x, y, nx, ny)
-}
-
 // ------------------ GOROUTINES AUTÔNOMAS ------------------
 
+// Guarda, portal e armadilha são simulados pelo servidor (server_elementos.go);
+// o cliente apenas acompanha o estado publicado.
+// Sem o recurso de stream acordado, o estado chega apenas pelo polling.
 func iniciarElementos(jogo *Jogo) {
-    go comportamentoGuarda(guarda, jogo)
-    go comportamentoPortal(portal, jogo)
-    go comportamentoArmadilha(armadilha, jogo)
-    go loopAtualizacaoCliente(jogo, clienteRPC, clientID)
-}
-
-// Troca duas células do mapa (para NPCs)
-func jogoTrocar(jogo *Jogo, x, y, nx, ny int) {
-    jogo.Mapa[y][x], jogo.Mapa[ny][nx] = jogo.Mapa[ny][nx], jogo.Mapa[y][x]
-}
-
-func comportamentoGuarda(guarda *Guarda, jogo *Jogo) {
-
-    guarda.Elemento = Elemento{'G', CorAmarelo, CorPadrao, true}
-    withMapaLock(func() {
-        guarda.X, guarda.Y = 2, 2
-        if guarda.Y < 0 || guarda.Y >= len(jogo.Mapa) ||
-            guarda.X < 0 || guarda.X >= len(jogo.Mapa[guarda.Y]) ||
-            jogo.Mapa[guarda.Y][guarda.X].tangivel ||
-            (guarda.X == jogo.PosX && guarda.Y == jogo.PosY) {
-            found := false
-            for y := 0; y < len(jogo.Mapa) && !found; y++ {
-                for x := 0; x < len(jogo.Mapa[y]); x++ {
-                    if !jogo.Mapa[y][x].tangivel && !(x == jogo.PosX && y == jogo.PosY) {
-                        guarda.X, guarda.Y = x, y
-                        found = true
-                        break
-                    }
-                }
-            }
-        }
-        jogo.Mapa[guarda.Y][guarda.X] = guarda.Elemento
-    })
-
-    rand.Seed(time.Now().UnixNano())
-
-    for {
-        stop := false
-        withMapaLock(func() {
-            if jogo.GameOver {
-                stop = true
-            }
-        })
-        if stop {
-            return
-        }
-
-        moved := false
-
-        select {
-        case <-guarda.Perseguir:
-            withMapaLock(func() {
-                dx, dy := 0, 0
-                if jogo.PosX > guarda.X {
-                    dx = 1
-                } else if jogo.PosX < guarda.X {
-                    dx = -1
-                }
-                if jogo.PosY > guarda.Y {
-                    dy = 1
-                } else if jogo.PosY < guarda.Y {
-                    dy = -1
-                }
-                nx, ny := guarda.X+dx, guarda.Y+dy
-                if !(nx == jogo.PosX && ny == jogo.PosY) &&
-                    ny >= 0 && ny < len(jogo.Mapa) && nx >= 0 && nx < len(jogo.Mapa[ny]) &&
-                    !jogo.Mapa[ny][nx].tangivel && jogo.Mapa[ny][nx].simbolo != 'O' {
-                    jogo.Mapa[guarda.Y][guarda.X] = guarda.Elemento
-                    guarda.X, guarda.Y = nx, ny
-                    moved = true
-                }
-            })
-        case <-guarda.PararPerseguicao:
-        default:
-            dx := rand.Intn(3) - 1
-            dy := rand.Intn(3) - 1
-            withMapaLock(func() {
-                nx, ny := guarda.X+dx, guarda.Y+dy
-                if !(nx == jogo.PosX && ny == jogo.PosY) &&
-                    ny >= 0 && ny < len(jogo.Mapa) && nx >= 0 && nx < len(jogo.Mapa[ny]) &&
-                    !jogo.Mapa[ny][nx].tangivel && jogo.Mapa[ny][nx].simbolo != 'O' {
-                    jogoTrocar(jogo, guarda.X, guarda.Y, nx, ny)
-                    guarda.X, guarda.Y = nx, ny
-                    moved = true
-                }
-            })
-        }
-
-        if moved {
-            time.Sleep(300 * time.Millisecond)
-        } else {
-            time.Sleep(120 * time.Millisecond)
-        }
+    // Definido antes das goroutines: uma reconexão iniciada por elas já ressincroniza o jogo
+    clienteDefinirJogo(jogo)
+    if clienteTemRecurso(RecursoStream) {
+        go loopStreamCliente(jogo, enderecoServidor)
     }
+    go loopAtualizacaoCliente(jogo)
 }
 
+// jogoReiniciar volta ao jogo após o fim de jogo. O terreno nunca é alterado e a
+// posição do personagem vem do servidor, então não há nada a remontar.
 func jogoReiniciar(jogo *Jogo) error {
-    jogo.GameOver = true
-    time.Sleep(50 * time.Millisecond)
-
-    guarda = &Guarda{
-        Elemento:         Elemento{'G', CorAmarelo, CorPadrao, true},
-        Perseguir:        make(chan bool),
-        PararPerseguicao: make(chan bool),
-    }
-    portal = &Portal{
-        Elemento:            Elemento{'P', CorCiano, CorPadrao, false},
-        Teletransportar:     make(chan bool),
-        PararTeletransporte: make(chan bool),
-    }
-    armadilha = &Armadilha{
-        Elemento:           Elemento{'A', CorVermelho, CorPadrao, false},
-        ProximidadeJogador: make(chan bool),
-        ProximidadeOutro:   make(chan bool),
-        PararArmadilha:     make(chan bool),
-    }
-    jogo.Mapa = nil
-    jogo.MapaStatic = nil // Limpa o mapa estático também
-    jogo.UltimoVisitado = Vazio
-    if err := jogoCarregarMapa("mapa.txt", jogo); err != nil {
-        return err
+    if len(jogo.Mapa.Terreno) == 0 {
+        return fmt.Errorf("mapa não carregado")
     }
 
     jogo.GameOver = false
     jogo.StatusMsg = "Jogo reiniciado."
-    iniciarElementos(jogo)
     return nil
 }
 
-func comportamentoArmadilha(armadilha *Armadilha, jogo *Jogo) {
-    withMapaLock(func() {
-        armadilha.X, armadilha.Y = 6, 6
-        if armadilha.Y < len(jogo.Mapa) && armadilha.X < len(jogo.Mapa[armadilha.Y]) {
-            if jogo.Mapa[armadilha.Y][armadilha.X].simbolo == armadilha.Elemento.simbolo {
-                jogo.Mapa[armadilha.Y][armadilha.X] = Vazio
-            }
-        }
-    })
-
-    rand.Seed(time.Now().UnixNano())
+// ------------------ FUNÇÕES CLIENTE MULTIPLAYER ------------------
+// loopAtualizacaoCliente busca o estado do jogo no servidor periodicamente e atualiza o estado local.
+// Enquanto o stream de estado estiver ativo (loopStreamCliente), a consulta passa a ser feita
+// apenas a cada intervaloHeartbeat, para manter a sessão viva no servidor: o polling
+// fica como alternativa caso o stream caia.
+func loopAtualizacaoCliente(jogo *Jogo) {
+    ticker := time.NewTicker(200 * time.Millisecond)
+    defer ticker.Stop()
 
-    for {
-        stop := false
-        withMapaLock(func() {
-            if jogo.GameOver {
-                stop = true
-            }
-        })
-        if stop {
-            return
+    var ultimaConsulta time.Time
+    for range ticker.C {
+        if streamAtivo.Load() && time.Since(ultimaConsulta) < intervaloHeartbeat {
+            continue
         }
+        ultimaConsulta = time.Now()
 
-        select {
-        case <-armadilha.ProximidadeJogador:
-            // A mensagem é tratada no cliente (personagem.go)
-        case <-armadilha.ProximidadeOutro:
-            withMapaLock(func() { jogo.StatusMsg = "Outro elemento acionou a armadilha!" })
-        case <-armadilha.PararArmadilha:
-            withMapaLock(func() { jogo.StatusMsg = "A armadilha foi desativada." })
-            return
-        default:
-            // MOVE A ARMADILHA ALEATORIAMENTE PELO MAPA
-            withMapaLock(func() {
-                altura := len(jogo.Mapa)
-                if altura == 0 {
-                    return
-                }
-                largura := len(jogo.Mapa[0])
-
-                for tentativas := 0; tentativas < 100; tentativas++ {
-                    x := rand.Intn(largura)
-                    y := rand.Intn(altura)
-                    elem := jogo.Mapa[y][x]
-                    if !elem.tangivel && elem.simbolo == ' ' {
-                        // Limpa posição anterior
-                        if armadilha.Y < len(jogo.Mapa) && armadilha.X < len(jogo.Mapa[armadilha.Y]) {
-                            jogo.Mapa[armadilha.Y][armadilha.X] = Vazio
-                        }
-                        // Move a armadilha
-                        armadilha.X, armadilha.Y = x, y
-                        jogo.Mapa[y][x] = armadilha.Elemento
-                        jogo.StatusMsg = fmt.Sprintf("⚠️ A armadilha se moveu para (%d, %d)!", x, y)
-                        break
-                    }
-                }
-            })
+        comando := Comando{
+            ClientID: clienteID(),
+            Acao:     "BuscarEstado",
+        }
+        withMapaLock(func() { comando.UltimoTick = jogo.Servidor.Tick })
+        var resposta Resposta
 
-            // Espera 5 segundos antes de mover de novo
-            time.Sleep(10 * time.Second)
+        err := clienteChamar("JogoServer.BuscarEstado", comando, &resposta)
+        if err != nil {
+            // Se falhar, tenta novamente no próximo tick (a reconexão é automática)
+            continue 
         }
-    }
-}
 
-func comportamentoPortal(portal *Portal, jogo *Jogo) {
-    // Define posição inicial do portal
-    withMapaLock(func() {
-        portal.X, portal.Y = 5, 5 // posição inicial arbitrária
-        if portal.Y < len(jogo.Mapa) && portal.X < len(jogo.Mapa[portal.Y]) {
-            if jogo.Mapa[portal.Y][portal.X].simbolo == portal.Elemento.simbolo {
-                jogo.Mapa[portal.Y][portal.X] = Vazio
+        // O servidor não reconhece mais a sessão: registra de novo, sem esperar a reconexão
+        if resposta.Codigo == CodigoNaoRegistrado || resposta.Motivo == MotivoTokenInvalido {
+            if err := clienteRenovarSessao(); err != nil {
+                withMapaLock(func() {
+                    jogo.StatusMsg = "Sessão perdida: " + err.Error()
+                    interfaceDesenharJogo(jogo)
+                })
             }
+            continue
         }
-    })
-    rand.Seed(time.Now().UnixNano())
 
-    for {
-        stop := false
         withMapaLock(func() {
-            if jogo.GameOver {
-                stop = true
+            // A consulta de estado só traz mensagem quando há algo a informar (ex.: sessão expirada)
+            if resposta.Mensagem != "" {
+                jogo.StatusMsg = resposta.Mensagem
             }
+            jogoAplicarDelta(jogo, resposta.Delta)
+            interfaceDesenharJogo(jogo)
         })
-        if stop {
-            return
-        }
+    }
+}
 
-        select {
-        case <-portal.PararTeletransporte:
-            withMapaLock(func() { jogo.StatusMsg = "Portal desativado." })
-            return
-        default:
-            // Escolhe nova posição aleatória no mapa
-            withMapaLock(func() {
-                altura := len(jogo.Mapa)
-                if altura == 0 {
-                    return
-                }
-                largura := len(jogo.Mapa[0])
-
-                for tentativas := 0; tentativas < 100; tentativas++ {
-                    x := rand.Intn(largura)
-                    y := rand.Intn(altura)
-                    elem := jogo.Mapa[y][x]
-                    if !elem.tangivel && elem.simbolo == ' ' {
-                        // Limpa posição anterior
-                        if portal.Y < len(jogo.Mapa) && portal.X < len(jogo.Mapa[portal.Y]) {
-                            jogo.Mapa[portal.Y][portal.X] = Vazio
-                        }
-                        // Move o portal
-                        portal.X, portal.Y = x, y
-                        jogo.Mapa[y][x] = portal.Elemento
-                        jogo.StatusMsg = fmt.Sprintf("⚡ O portal se moveu para (%d, %d)!", x, y)
+// loopStreamCliente se inscreve no stream de estado do servidor e aplica cada
+// atualização assim que ela chega. Se a conexão cair, tenta novamente com backoff.
+func loopStreamCliente(jogo *Jogo, endereco string) {
+    atraso := atrasoReconexaoInicial
+    for {
+        conn, err := net.Dial("tcp", endereco)
+        if err == nil {
+            atraso = atrasoReconexaoInicial
+            if _, err = fmt.Fprintf(conn, "%s%s %s\n", PrefixoStream, clienteID(), clienteToken()); err == nil {
+                streamAtivo.Store(true)
+                dec := gob.NewDecoder(conn)
+                for {
+                    var delta DeltaEstado
+                    if err := dec.Decode(&delta); err != nil {
                         break
                     }
+                    aplicado := false
+                    var tickLocal uint64
+                    withMapaLock(func() {
+                        aplicado = jogoAplicarDelta(jogo, delta)
+                        tickLocal = jogo.Servidor.Tick
+                        interfaceDesenharJogo(jogo)
+                    })
+                    // Delta com base diferente da local: informa ao servidor de onde partir
+                    if !aplicado && delta.Tick > tickLocal {
+                        fmt.Fprintf(conn, "%s%d\n", PrefixoAck, tickLocal)
+                    }
                 }
-            })
-
-            // Espera antes de se mover novamente
-            time.Sleep(15 * time.Second)
+                streamAtivo.Store(false)
+            }
+            conn.Close()
+        }
+        time.Sleep(atraso)
+        atraso *= 2
+        if atraso > atrasoReconexaoMaximo {
+            atraso = atrasoReconexaoMaximo
         }
     }
 }
 
-// ------------------ FUNÇÕES CLIENTE MULTIPLAYER ------------------
-// loopAtualizacaoCliente busca o estado do jogo no servidor periodicamente e atualiza o estado local.
-func loopAtualizacaoCliente(jogo *Jogo, clienteRPC *rpc.Client, clientID string) {
-    ticker := time.NewTicker(200 * time.Millisecond)
-    defer ticker.Stop()
-
-    for range ticker.C {
-        stop := false
-        withMapaLock(func() {
-            if jogo.GameOver {
-                stop = true
-            }
-        })
-        if stop {
-            return
+// jogoAplicarResposta aplica o estado anexado a uma resposta do servidor. Ao entrar
+// numa sala, a resposta traz também o mapa dela, que substitui o mapa local.
+// Deve ser chamada com o mapa travado.
+func jogoAplicarResposta(jogo *Jogo, resposta Resposta) bool {
+    if len(resposta.Mapa) > 0 {
+        if err := jogoMontarMapa(resposta.Mapa, jogo); err != nil {
+            jogo.StatusMsg = "Mapa da sala inválido: " + err.Error()
         }
+    }
+    return jogoAplicarDelta(jogo, resposta.Delta)
+}
 
-        comando := Comando{
-            ClientID: clientID,
-            Acao:     "BuscarEstado",
+// jogoAplicarEventos reage aos acontecimentos do tick que envolvem o jogador local.
+// Deve ser chamada com o mapa travado.
+func jogoAplicarEventos(jogo *Jogo, eventos []Evento) {
+    meuID := clienteJogadorID()
+    for _, evento := range eventos {
+        // Perder a última vida libera o reinício (tecla R) sem esperar o próximo estado
+        if evento.Jogador == meuID && evento.Tipo == EventoFimDeJogo {
+            jogo.GameOver = true
         }
-        var resposta Resposta
+    }
+}
 
-        err := clienteRPC.Call("JogoServer.BuscarEstado", comando, &resposta)
-        if err != nil {
-            // Se falhar, tenta novamente no próximo tick
-            continue 
+// jogoAplicarDelta aplica um delta recebido do servidor à cópia local do estado
+// e sincroniza o jogo: adota posição e vidas do jogador local e atualiza as camadas
+// com os jogadores e as entidades. Retorna false se o delta não partir do tick local.
+// Deve ser chamada com o mapa travado.
+func jogoAplicarDelta(jogo *Jogo, delta DeltaEstado) bool {
+    mapaTick, avisoTick := jogo.Servidor.MapaTick, jogo.Servidor.AvisoTick
+    if !aplicarDelta(&jogo.Servidor, delta) {
+        return false
+    }
+    estado := jogo.Servidor
+
+    // Salas com mapa gerado trocam de mapa a cada rodada
+    if len(delta.Mapa) > 0 && delta.MapaTick != mapaTick {
+        if err := jogoMontarMapa(delta.Mapa, jogo); err != nil {
+            jogo.StatusMsg = "Mapa da sala inválido: " + err.Error()
         }
+    }
 
-        withMapaLock(func() {
-            // 💡 CORREÇÃO DE MENSAGENS: Apenas atualiza a mensagem se não for uma das mensagens padrão do servidor.
-            if resposta.Mensagem != "Estado atual enviado." && resposta.Mensagem != "Posição e Vidas atualizadas..." && resposta.Mensagem != "Posição atualizada..." {
-                jogo.StatusMsg = resposta.Mensagem
-            }
-            
-            // 1. Limpa todos os outros jogadores da tela antes de redesenhar
-            jogoLimparJogadores(jogo)
-
-            // 2. Itera sobre a lista completa de jogadores fornecida pelo servidor
-            for outroID, jogadorEstado := range resposta.EstadoAtual.Jogadores {
-                
-                // 2a. Sincroniza o Estado do Jogador Local
-                if outroID == clientID {
-                    // O servidor é a fonte da verdade para Vidas/Posição.
-                    jogo.Vidas = jogadorEstado.Vidas
-                    jogo.PosX = jogadorEstado.X 
-                    jogo.PosY = jogadorEstado.Y
-                    continue 
-                }
+    // Avisos do servidor (entrada e saída de jogadores) aparecem na barra de status uma
+    // vez: um snapshot completo repete o último aviso, já mostrado
+    if delta.AvisoTick > avisoTick && delta.Aviso != "" {
+        jogo.StatusMsg = delta.Aviso
+    }
 
-                // 2b. Desenha Outros Jogadores
-                if jogadorEstado.Y >= 0 && jogadorEstado.Y < len(jogo.Mapa) &&
-                    jogadorEstado.X >= 0 && jogadorEstado.X < len(jogo.Mapa[jogadorEstado.Y]) {
-                    
-                    // Desenha o outro jogador (símbolo ☺)
-                    jogo.Mapa[jogadorEstado.Y][jogadorEstado.X] = Elemento{
-                        simbolo:  '☺',
-                        cor:      CorAzul,
-                        corFundo: CorPadrao,
-                        tangivel: true,
-                    }
-                }
-            }
-            interfaceDesenharJogo(jogo)
-        })
+    // O servidor é a fonte da verdade para Vidas/Posição do jogador local
+    meuID := clienteJogadorID()
+    if jogadorEstado, ok := estado.Jogadores[meuID]; ok {
+        jogo.Vidas = jogadorEstado.Vidas
+        jogo.PosX = jogadorEstado.X
+        jogo.PosY = jogadorEstado.Y
     }
+
+    // Jogadores e entidades do delta vão para as camadas, pelo ID
+    jogoAplicarCamadas(jogo, delta)
+    return true
 }
 
-// 💡 NOVO: Usa MapaStatic para restaurar o elemento de fundo original
-func jogoLimparJogadores(jogo *Jogo) {
-    for y := 0; y < len(jogo.Mapa); y++ {
-        for x := 0; x < len(jogo.Mapa[y]); x++ {
-            // Limpa APENAS o símbolo do Outro Jogador '☺'
-            if jogo.Mapa[y][x].simbolo == '☺' { 
-                
-                // Restaura o elemento de fundo (P, A, Vazio, etc.) da cópia estática
-                if y < len(jogo.MapaStatic) && x < len(jogo.MapaStatic[y]) {
-                    jogo.Mapa[y][x] = jogo.MapaStatic[y][x] 
-                } else {
-                    jogo.Mapa[y][x] = Vazio // Fallback
-                }
-            }
-            // Limpa o Guarda (G) para que ele seja redesenhado na próxima posição
-            if jogo.Mapa[y][x].simbolo == guarda.Elemento.simbolo {
-                // Restaura o elemento de fundo (P, A, Vazio, etc.) da cópia estática
-                if y < len(jogo.MapaStatic) && x < len(jogo.MapaStatic[y]) {
-                    jogo.Mapa[y][x] = jogo.MapaStatic[y][x] 
-                } else {
-                    jogo.Mapa[y][x] = Vazio // Fallback
-                }
-            }
+// Prefixos dos IDs nas camadas, para que jogadores e entidades do servidor não se confundam
+const (
+    prefixoCamadaJogador  = "jogador/"
+    prefixoCamadaEntidade = "entidade/"
+)
+
+// jogoAplicarCamadas atualiza as camadas de itens e entidades com o que mudou no delta:
+// cada jogador e cada entidade autônoma é um objeto da sua camada, movido ou removido
+// pelo ID. Um delta completo recomeça as camadas. Deve ser chamada com o mapa travado.
+func jogoAplicarCamadas(jogo *Jogo, delta DeltaEstado) {
+    if delta.Completo {
+        jogo.Mapa.limpar(CamadaItens)
+        jogo.Mapa.limpar(CamadaEntidades)
+    }
+    meuID := clienteJogadorID()
+    for id, jogador := range delta.Jogadores {
+        elem := OutroJogador
+        if id == meuID && !jogo.Espectador {
+            elem = Personagem
         }
+        jogo.Mapa.definir(CamadaEntidades, prefixoCamadaJogador+id, elem, jogador.X, jogador.Y)
+    }
+    for _, id := range delta.JogadoresRemovidos {
+        jogo.Mapa.remover(CamadaEntidades, prefixoCamadaJogador+id)
+    }
+    for id, entidade := range delta.Entidades {
+        jogo.Mapa.definir(camadaEntidade[entidade.Tipo], prefixoCamadaEntidade+id,
+            jogoElementoEntidade(entidade.Tipo), entidade.X, entidade.Y)
+    }
+    for _, id := range delta.EntidadesRemovidas {
+        jogo.Mapa.remover(CamadaItens, prefixoCamadaEntidade+id)
+        jogo.Mapa.remover(CamadaEntidades, prefixoCamadaEntidade+id)
     }
 }
 
-func jogoEncontrarSaida(mapa [][]Elemento, origemX, origemY int) (int, int) {
-	rand.Seed(time.Now().UnixNano())
+// jogoElementoEntidade retorna o elemento visual de um tipo de entidade do servidor.
+func jogoElementoEntidade(tipo string) Elemento {
+    switch tipo {
+    case TipoGuarda:
+        return ElementoGuarda
+    case TipoPortal:
+        return ElementoPortal
+    case TipoArmadilha:
+        return ElementoArmadilha
+    }
+    return Vazio
+}
 
+// jogoEncontrarSaida sorteia uma posição vazia do mapa usando a fonte aleatória informada.
+// A largura é a de cada linha sorteada: o mapa não precisa ser retangular.
+func jogoEncontrarSaida(mapa [][]Elemento, origemX, origemY int, rng *rand.Rand) (int, int) {
 	altura := len(mapa)
 	if altura == 0 {
 		return origemX, origemY
 	}
-	largura := len(mapa[0])
 
 	for tentativas := 0; tentativas < 100; tentativas++ {
-		x := rand.Intn(largura)
-		y := rand.Intn(altura)
+		y := rng.Intn(altura)
+		if len(mapa[y]) == 0 {
+			continue
+		}
+		x := rng.Intn(len(mapa[y]))
 
 		elem := mapa[y][x]
 
@@ -566,6 +404,6 @@ func jogoEncontrarSaida(mapa [][]Elemento, origemX, origemY int) (int, int) {
 	return origemX, origemY
 }
 
-//  $ go run cliente.go jogo.go Structs.go interface.go personagem.go
-// go build -o server_jogo server_jogo.go server.go Structs.go 
-// ./server_jogo
\ No newline at end of file
+//  $ go build -o jogo && ./jogo -nome Ana -endereco localhost:1234
+// go build -tags servidor -o server_jogo
+// ./server_jogo -porta 1234 -mapa mapa.txt (ou ./server_jogo -config jogo.conf)
\ No newline at end of file
@@ -0,0 +1,371 @@
+// mapa.go - Formato dos arquivos de mapa: cabeçalho opcional, legenda e grade
+// Um mapa pode começar com um cabeçalho entre duas linhas "---", com uma declaração
+// "chave: valor" por linha (linhas vazias e iniciadas por # são ignoradas):
+//
+//	---
+//	nome: Labirinto
+//	autor: Ana
+//	tamanho: 80x30
+//	spawn: 3,1 5,1
+//	guarda: 40,15
+//	portal: 2,3 77,27
+//	armadilha: 10,5 30,9
+//	legenda: # = parede
+//	---
+//
+// As posições são "x,y", a partir de 0. Cada linha "portal" declara um portal avulso,
+// cuja saída é sorteada, ou um par, em que cada portal leva ao outro.
+//
+// O restante do arquivo é a grade. Cada símbolo é traduzido pela legenda: a padrão
+// (legendaPadrao) mais as linhas "legenda" do cabeçalho. Os símbolos de spawn e de
+// entidades (guarda, portal, armadilha) declaram aquilo naquela célula, que fica vazia
+// no terreno. Um mapa sem cabeçalho é só a grade, com a legenda padrão.
+// Símbolos fora da legenda, declarações inválidas e os problemas apontados por
+// mapaValidar (mapa_validacao.go) são erros com linha e coluna.
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Linha que abre e fecha o cabeçalho do mapa
+const separadorCabecalho = "---"
+
+// Tipos de célula que a legenda associa aos símbolos. Os tipos das entidades
+// autônomas (TipoGuarda, TipoPortal, TipoArmadilha) também valem na legenda.
+const (
+	CelulaVazio     = "vazio"
+	CelulaParede    = "parede"
+	CelulaVegetacao = "vegetacao"
+	CelulaInimigo   = "inimigo"
+	CelulaSpawn     = "spawn"
+)
+
+// terrenoCelula é o elemento de terreno de cada tipo de célula. Spawns e entidades
+// ficam sobre células vazias.
+var terrenoCelula = map[string]Elemento{
+	CelulaVazio:     Vazio,
+	CelulaParede:    Parede,
+	CelulaVegetacao: Vegetacao,
+	CelulaInimigo:   Inimigo,
+	CelulaSpawn:     Vazio,
+	TipoGuarda:      Vazio,
+	TipoPortal:      Vazio,
+	TipoArmadilha:   Vazio,
+}
+
+// legendaPadrao retorna a legenda dos mapas sem cabeçalho: os símbolos desenhados
+// pelo cliente de terminal.
+func legendaPadrao() map[rune]string {
+	return map[rune]string{
+		Vazio.simbolo:             CelulaVazio,
+		Parede.simbolo:            CelulaParede,
+		Vegetacao.simbolo:         CelulaVegetacao,
+		Inimigo.simbolo:           CelulaInimigo,
+		Personagem.simbolo:        CelulaSpawn,
+		ElementoGuarda.simbolo:    TipoGuarda,
+		ElementoPortal.simbolo:    TipoPortal,
+		ElementoArmadilha.simbolo: TipoArmadilha,
+	}
+}
+
+// PortalMapa é um portal declarado no mapa. Saida é a posição do portal ligado
+// a ele, ou nil se a saída for sorteada a cada uso.
+type PortalMapa struct {
+	Posicao
+	Saida *Posicao
+}
+
+// InfoMapa reúne o que o arquivo declara além do terreno e dos spawns.
+type InfoMapa struct {
+	Nome       string
+	Autor      string
+	Largura    int // Tamanho declarado no cabeçalho (0: não declarado)
+	Altura     int
+	Guardas    []Posicao
+	Portais    []PortalMapa
+	Armadilhas []Posicao
+	LinhaGrade int // Linha do arquivo (a partir de 1) em que a grade começa
+}
+
+// ErroMapa é um problema encontrado num arquivo de mapa.
+type ErroMapa struct {
+	Linha  int // Linha do arquivo, a partir de 1
+	Coluna int // Coluna (em caracteres), a partir de 1; 0 se o problema for a linha toda
+	Msg    string
+}
+
+func (e ErroMapa) Error() string {
+	if e.Coluna == 0 {
+		return fmt.Sprintf("linha %d: %s", e.Linha, e.Msg)
+	}
+	return fmt.Sprintf("linha %d, coluna %d: %s", e.Linha, e.Coluna, e.Msg)
+}
+
+// ErrosMapa reúne todos os problemas encontrados num mapa, na ordem do arquivo.
+type ErrosMapa []ErroMapa
+
+func (e ErrosMapa) Error() string {
+	partes := make([]string, len(e))
+	for i, erro := range e {
+		partes[i] = erro.Error()
+	}
+	return strings.Join(partes, "; ")
+}
+
+// ordenar põe os problemas na ordem do arquivo: por linha e, na mesma linha, por coluna.
+func (e ErrosMapa) ordenar() {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].Linha != e[j].Linha {
+			return e[i].Linha < e[j].Linha
+		}
+		return e[i].Coluna < e[j].Coluna
+	})
+}
+
+// mapaLido é o resultado da leitura das linhas de um arquivo de mapa.
+type mapaLido struct {
+	terreno [][]Elemento
+	spawns  []Posicao // Declarados no cabeçalho e na grade, nessa ordem
+	info    InfoMapa
+	avisos  ErrosMapa // Problemas que não impedem o uso do mapa (mapa_validacao.go)
+
+	// Linhas do cabeçalho que declararam o tamanho, cada posição e cada símbolo da
+	// legenda, para os diagnósticos
+	linhaTamanho int
+	declaradas   map[Posicao]int
+	simbolos     map[rune]int
+}
+
+// mapaInterpretar lê o cabeçalho, se houver, e a grade de um arquivo de mapa e confere
+// o resultado (mapa_validacao.go). Linhas vazias no fim do arquivo não fazem parte da grade.
+// Todos os erros encontrados são retornados juntos, em ErrosMapa; os avisos ficam em m.avisos.
+func mapaInterpretar(linhas []string) (mapaLido, error) {
+	m := mapaLido{declaradas: make(map[Posicao]int), simbolos: make(map[rune]int)}
+	legenda := legendaPadrao()
+	inicio, erros := mapaLerCabecalho(linhas, &m, legenda)
+	m.info.LinhaGrade = inicio + 1
+
+	fim := len(linhas)
+	for fim > inicio && strings.TrimSpace(linhas[fim-1]) == "" {
+		fim--
+	}
+	for y, linha := range linhas[inicio:fim] {
+		var elementos []Elemento
+		for x, ch := range []rune(linha) {
+			tipo, ok := legenda[ch]
+			if !ok {
+				erros = append(erros, ErroMapa{inicio + y + 1, x + 1, fmt.Sprintf("símbolo %q fora da legenda", ch)})
+				tipo = CelulaVazio
+			}
+			p := Posicao{x, y}
+			switch tipo {
+			case CelulaSpawn:
+				m.spawns = append(m.spawns, p)
+			case TipoGuarda:
+				m.info.Guardas = append(m.info.Guardas, p)
+			case TipoPortal:
+				m.info.Portais = append(m.info.Portais, PortalMapa{Posicao: p})
+			case TipoArmadilha:
+				m.info.Armadilhas = append(m.info.Armadilhas, p)
+			}
+			elementos = append(elementos, terrenoCelula[tipo])
+		}
+		m.terreno = append(m.terreno, elementos)
+	}
+	errosGrade, avisos := mapaValidar(m)
+	erros = append(erros, errosGrade...)
+	erros.ordenar()
+	avisos.ordenar()
+	m.avisos = avisos
+	if len(erros) > 0 {
+		return m, erros
+	}
+	return m, nil
+}
+
+// mapaLerCabecalho interpreta o cabeçalho, se as linhas começarem por um, e retorna
+// o índice da primeira linha da grade. As declarações vão para m e para a legenda.
+func mapaLerCabecalho(linhas []string, m *mapaLido, legenda map[rune]string) (int, ErrosMapa) {
+	if len(linhas) == 0 || strings.TrimSpace(linhas[0]) != separadorCabecalho {
+		return 0, nil
+	}
+	var erros ErrosMapa
+	for i := 1; i < len(linhas); i++ {
+		linha := strings.TrimSpace(linhas[i])
+		if linha == separadorCabecalho {
+			return i + 1, erros
+		}
+		if linha == "" || strings.HasPrefix(linha, "#") {
+			continue
+		}
+		if err := mapaLerDeclaracao(linha, i+1, m, legenda); err != "" {
+			erros = append(erros, ErroMapa{Linha: i + 1, Msg: err})
+		}
+	}
+	return len(linhas), append(erros, ErroMapa{Linha: 1, Msg: "cabeçalho sem a linha " + separadorCabecalho + " de fechamento"})
+}
+
+// mapaLerDeclaracao aplica a linha n, "chave: valor", do cabeçalho.
+// Retorna a descrição do problema, ou "" se a declaração for válida.
+func mapaLerDeclaracao(linha string, n int, m *mapaLido, legenda map[rune]string) string {
+	chave, valor, ok := strings.Cut(linha, ":")
+	if !ok {
+		return "declaração sem \":\""
+	}
+	chave, valor = strings.ToLower(strings.TrimSpace(chave)), strings.TrimSpace(valor)
+
+	switch chave {
+	case "nome":
+		m.info.Nome = valor
+	case "autor":
+		m.info.Autor = valor
+	case "tamanho":
+		var largura, altura int
+		if lidos, _ := fmt.Sscanf(valor, "%dx%d", &largura, &altura); lidos != 2 || largura <= 0 || altura <= 0 {
+			return fmt.Sprintf("tamanho inválido %q (use LARGURAxALTURA)", valor)
+		}
+		m.info.Largura, m.info.Altura = largura, altura
+		m.linhaTamanho = n
+	case CelulaSpawn, TipoGuarda, TipoArmadilha:
+		posicoes, err := mapaLerPosicoes(valor)
+		if err != "" {
+			return err
+		}
+		for _, p := range posicoes {
+			m.declaradas[p] = n
+		}
+		switch chave {
+		case CelulaSpawn:
+			m.spawns = append(m.spawns, posicoes...)
+		case TipoGuarda:
+			m.info.Guardas = append(m.info.Guardas, posicoes...)
+		case TipoArmadilha:
+			m.info.Armadilhas = append(m.info.Armadilhas, posicoes...)
+		}
+	case TipoPortal:
+		posicoes, err := mapaLerPosicoes(valor)
+		if err != "" {
+			return err
+		}
+		for _, p := range posicoes {
+			m.declaradas[p] = n
+		}
+		switch len(posicoes) {
+		case 1:
+			m.info.Portais = append(m.info.Portais, PortalMapa{Posicao: posicoes[0]})
+		case 2:
+			a, b := posicoes[0], posicoes[1]
+			m.info.Portais = append(m.info.Portais, PortalMapa{a, &b}, PortalMapa{b, &a})
+		default:
+			return "portal: informe uma posição (saída sorteada) ou um par de posições"
+		}
+	case "legenda":
+		return mapaLerLegenda(valor, n, m, legenda)
+	default:
+		return fmt.Sprintf("chave desconhecida %q", chave)
+	}
+	return ""
+}
+
+// mapaLerPosicoes lê uma lista de posições "x,y" separadas por espaços.
+func mapaLerPosicoes(valor string) ([]Posicao, string) {
+	campos := strings.Fields(valor)
+	if len(campos) == 0 {
+		return nil, "nenhuma posição informada"
+	}
+	posicoes := make([]Posicao, 0, len(campos))
+	for _, campo := range campos {
+		sx, sy, ok := strings.Cut(campo, ",")
+		x, errX := strconv.Atoi(sx)
+		y, errY := strconv.Atoi(sy)
+		if !ok || errX != nil || errY != nil || x < 0 || y < 0 {
+			return nil, fmt.Sprintf("posição inválida %q (use x,y)", campo)
+		}
+		posicoes = append(posicoes, Posicao{x, y})
+	}
+	return posicoes, ""
+}
+
+// mapaLerLegenda lê "<símbolo> = <tipo>", da linha n do cabeçalho, e associa o símbolo
+// ao tipo na legenda. Um símbolo da legenda padrão pode ser trocado, mas cada símbolo
+// só pode ser declarado uma vez no cabeçalho.
+func mapaLerLegenda(valor string, n int, m *mapaLido, legenda map[rune]string) string {
+	simbolos := []rune(valor)
+	if len(simbolos) == 0 {
+		return "legenda vazia (use <símbolo> = <tipo>)"
+	}
+	resto := strings.TrimSpace(string(simbolos[1:]))
+	if !strings.HasPrefix(resto, "=") {
+		return fmt.Sprintf("legenda inválida %q (use <símbolo> = <tipo>)", valor)
+	}
+	tipo := strings.ToLower(strings.TrimSpace(resto[1:]))
+	if _, ok := terrenoCelula[tipo]; !ok {
+		return fmt.Sprintf("tipo desconhecido %q na legenda", tipo)
+	}
+	if linha, ok := m.simbolos[simbolos[0]]; ok {
+		return fmt.Sprintf("símbolo %q já declarado na legenda na linha %d", simbolos[0], linha)
+	}
+	legenda[simbolos[0]] = tipo
+	m.simbolos[simbolos[0]] = n
+	return ""
+}
+
+// mapaEscrever produz as linhas de um arquivo de mapa: o cabeçalho, com nome, autor,
+// tamanho, spawns e entidades, e a grade com os símbolos da legenda padrão. É o inverso
+// de mapaInterpretar; comentários e legendas do arquivo original não são preservados.
+func mapaEscrever(m mapaLido) []string {
+	linhas := []string{separadorCabecalho}
+	if m.info.Nome != "" {
+		linhas = append(linhas, "nome: "+m.info.Nome)
+	}
+	if m.info.Autor != "" {
+		linhas = append(linhas, "autor: "+m.info.Autor)
+	}
+	if len(m.terreno) > 0 {
+		linhas = append(linhas, fmt.Sprintf("tamanho: %dx%d", len(m.terreno[0]), len(m.terreno)))
+	}
+	declarar := func(chave string, posicoes []Posicao) {
+		if len(posicoes) > 0 {
+			linhas = append(linhas, chave+": "+mapaFormatarPosicoes(posicoes))
+		}
+	}
+	declarar(CelulaSpawn, m.spawns)
+	declarar(TipoGuarda, m.info.Guardas)
+	escritos := make(map[Posicao]bool)
+	for _, portal := range m.info.Portais {
+		if escritos[portal.Posicao] {
+			continue
+		}
+		escritos[portal.Posicao] = true
+		if portal.Saida == nil {
+			declarar(TipoPortal, []Posicao{portal.Posicao})
+			continue
+		}
+		escritos[*portal.Saida] = true
+		declarar(TipoPortal, []Posicao{portal.Posicao, *portal.Saida})
+	}
+	declarar(TipoArmadilha, m.info.Armadilhas)
+	linhas = append(linhas, separadorCabecalho)
+
+	for _, linha := range m.terreno {
+		simbolos := make([]rune, len(linha))
+		for x, elem := range linha {
+			simbolos[x] = elem.simbolo
+		}
+		linhas = append(linhas, string(simbolos))
+	}
+	return linhas
+}
+
+// mapaFormatarPosicoes formata posições como no cabeçalho: "x,y x,y".
+func mapaFormatarPosicoes(posicoes []Posicao) string {
+	partes := make([]string, len(posicoes))
+	for i, p := range posicoes {
+		partes[i] = fmt.Sprintf("%d,%d", p.X, p.Y)
+	}
+	return strings.Join(partes, " ")
+}
@@ -0,0 +1,93 @@
+// mapa_geracao.go - Mapas procedurais (pacote gerador): subcomando "generate" e opção -gerar
+// O subcomando "generate" do cliente e do servidor grava um mapa gerado num arquivo
+// .txt (ou na saída padrão), já conferido por mapaInterpretar:
+//
+//	./jogo generate -tipo labirinto -algoritmo prim -semente 42 -o arena.txt
+//
+// Com -gerar, o servidor gera o mapa da sala principal e um novo a cada rodada
+// (server_salas.go).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"T1fppd/gerador"
+)
+
+// executarGeracao trata o subcomando "generate" e retorna o código de saída do processo:
+// 0 se o mapa foi gravado, 1 se a geração falhou e 2 se as opções forem inválidas.
+func executarGeracao(args []string, saida io.Writer) int {
+	opcoes := gerador.OpcoesPadrao()
+	opcoes.Semente = time.Now().UnixNano()
+	var arquivo string
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(saida)
+	fs.StringVar(&opcoes.Tipo, "tipo", opcoes.Tipo, "tipo de mapa: "+strings.Join(gerador.Tipos, ", "))
+	fs.StringVar(&opcoes.Algoritmo, "algoritmo", opcoes.Algoritmo, "algoritmo do labirinto: "+strings.Join(gerador.Algoritmos, ", "))
+	fs.IntVar(&opcoes.Largura, "largura", opcoes.Largura, "largura do mapa")
+	fs.IntVar(&opcoes.Altura, "altura", opcoes.Altura, "altura do mapa")
+	fs.Int64Var(&opcoes.Semente, "semente", opcoes.Semente, "semente (a mesma semente gera o mesmo mapa)")
+	fs.StringVar(&opcoes.Nome, "nome", opcoes.Nome, "nome do mapa no cabeçalho")
+	fs.IntVar(&opcoes.Atalhos, "atalhos", opcoes.Atalhos, "paredes do labirinto derrubadas para criar caminhos alternativos")
+	fs.IntVar(&opcoes.Vegetacao, "vegetacao", opcoes.Vegetacao, "manchas de vegetação")
+	fs.IntVar(&opcoes.Inimigos, "inimigos", opcoes.Inimigos, "inimigos")
+	fs.IntVar(&opcoes.Spawns, "spawns", opcoes.Spawns, "pontos de spawn")
+	fs.IntVar(&opcoes.Guardas, "guardas", opcoes.Guardas, "guardas")
+	fs.IntVar(&opcoes.Portais, "portais", opcoes.Portais, "pares de portais")
+	fs.IntVar(&opcoes.Armadilhas, "armadilhas", opcoes.Armadilhas, "armadilhas")
+	fs.StringVar(&arquivo, "o", "", "arquivo de saída (vazio: saída padrão)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := opcoes.Validar(); err != nil {
+		fmt.Fprintln(saida, "Opções inválidas:", err)
+		return 2
+	}
+
+	linhas, err := gerarMapaConferido(opcoes)
+	if err != nil {
+		fmt.Fprintln(saida, "Erro ao gerar o mapa:", err)
+		return 1
+	}
+	texto := strings.Join(linhas, "\n") + "\n"
+	if arquivo == "" {
+		io.WriteString(saida, texto)
+		return 0
+	}
+	if err := os.WriteFile(arquivo, []byte(texto), 0o644); err != nil {
+		fmt.Fprintln(saida, "Erro ao gravar o mapa:", err)
+		return 1
+	}
+	fmt.Fprintf(saida, "%s: %s %dx%d, semente %d\n", arquivo, opcoes.Tipo, opcoes.Largura, opcoes.Altura, opcoes.Semente)
+	return 0
+}
+
+// gerarMapaConferido gera um mapa e o confere como se tivesse sido lido de um arquivo.
+func gerarMapaConferido(opcoes gerador.Opcoes) ([]string, error) {
+	linhas, err := gerador.Gerar(opcoes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mapaInterpretar(linhas); err != nil {
+		return nil, fmt.Errorf("mapa gerado inválido (semente %d): %w", opcoes.Semente, err)
+	}
+	return linhas, nil
+}
+
+// opcoesGeracao interpreta o valor de -gerar: "<tipo>" ou "labirinto:<algoritmo>".
+// As demais opções são as padrão do gerador.
+func opcoesGeracao(valor string) (gerador.Opcoes, error) {
+	opcoes := gerador.OpcoesPadrao()
+	tipo, algoritmo, ok := strings.Cut(valor, ":")
+	opcoes.Tipo = tipo
+	if ok {
+		opcoes.Algoritmo = algoritmo
+	}
+	return opcoes, opcoes.Validar()
+}
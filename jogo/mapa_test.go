@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMapaCabecalho(t *testing.T) {
+	grade := []string{"▤▤▤▤", "▤☺ ▤", "▤▤▤▤"}
+	comCabecalho := func(declaracoes ...string) []string {
+		linhas := append([]string{separadorCabecalho}, declaracoes...)
+		return append(append(linhas, separadorCabecalho), grade...)
+	}
+	casos := []struct {
+		nome   string
+		linhas []string
+		linha  int    // Linha do erro esperado; 0 se o mapa for válido
+		trecho string // Trecho da mensagem do erro esperado
+	}{
+		{nome: "cabeçalho válido", linhas: comCabecalho("nome: Teste", "# comentário", "", "tamanho: 4x3")},
+		{nome: "legenda troca símbolo padrão", linhas: comCabecalho("legenda: ▤ = vegetacao")},
+		{nome: "sem linha de fechamento", linhas: append([]string{separadorCabecalho, "nome: Teste"}, grade...),
+			linha: 1, trecho: "sem a linha --- de fechamento"},
+		{nome: "chave desconhecida", linhas: comCabecalho("nome: Teste", "cor: azul"),
+			linha: 3, trecho: `chave desconhecida "cor"`},
+		{nome: "declaração sem dois-pontos", linhas: comCabecalho("nome Teste"),
+			linha: 2, trecho: `declaração sem ":"`},
+		{nome: "símbolo repetido na legenda", linhas: comCabecalho("legenda: # = parede", "legenda: # = vazio"),
+			linha: 3, trecho: `símbolo '#' já declarado na legenda na linha 2`},
+		{nome: "legenda sem igual", linhas: comCabecalho("legenda: # parede"),
+			linha: 2, trecho: "legenda inválida"},
+		{nome: "legenda com tipo desconhecido", linhas: comCabecalho("legenda: # = lava"),
+			linha: 2, trecho: `tipo desconhecido "lava"`},
+		{nome: "legenda vazia", linhas: comCabecalho("legenda:"),
+			linha: 2, trecho: "legenda vazia"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			m, err := mapaInterpretar(c.linhas)
+			if c.linha == 0 {
+				if err != nil {
+					t.Fatalf("mapa recusado: %v", err)
+				}
+				if m.info.LinhaGrade != len(c.linhas)-len(grade)+1 {
+					t.Errorf("grade começa na linha %d, esperado %d", m.info.LinhaGrade, len(c.linhas)-len(grade)+1)
+				}
+				return
+			}
+			var erros ErrosMapa
+			if !errors.As(err, &erros) {
+				t.Fatalf("erro = %v, esperado ErrosMapa", err)
+			}
+			for _, e := range erros {
+				if e.Linha == c.linha && strings.Contains(e.Msg, c.trecho) {
+					return
+				}
+			}
+			t.Errorf("erros = %v, esperado na linha %d: %s", erros, c.linha, c.trecho)
+		})
+	}
+}
+
+func TestMapaLegenda(t *testing.T) {
+	// A legenda do cabeçalho acrescenta e troca símbolos; a grade usa os dois
+	linhas := []string{
+		separadorCabecalho,
+		"legenda: # = parede",
+		"legenda: ▤ = vegetacao",
+		"legenda: S = spawn",
+		separadorCabecalho,
+		"####",
+		"#S▤#",
+		"####",
+	}
+	m, err := mapaInterpretar(linhas)
+	if err != nil {
+		t.Fatalf("mapa recusado: %v", err)
+	}
+	if m.terreno[0][0] != Parede || m.terreno[1][2] != Vegetacao {
+		t.Errorf("terreno = %q %q, esperado parede e vegetação", m.terreno[0][0].simbolo, m.terreno[1][2].simbolo)
+	}
+	if len(m.spawns) != 1 || m.spawns[0] != (Posicao{1, 1}) {
+		t.Errorf("spawns = %v, esperado [(1, 1)]", m.spawns)
+	}
+}
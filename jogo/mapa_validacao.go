@@ -0,0 +1,210 @@
+// mapa_validacao.go - Conferência dos mapas e subcomando "validate"
+// Todo mapa carregado passa por mapaValidar, que aponta, com linha e coluna, os
+// problemas que estragariam uma partida: grade irregular ou diferente do tamanho
+// declarado, spawns e entidades fora do mapa, em células bloqueadas, sobrepostos
+// ou inalcançáveis a partir do spawn e saídas de portal sem passagem. Outros
+// problemas são apenas avisos, e o mapa carrega mesmo com eles: a falta de spawn
+// (o servidor usa a posição de -spawn) e regiões do terreno que não se alcançam a
+// partir do spawn. O subcomando "validate" do cliente e do servidor confere
+// arquivos de mapa sem iniciar o jogo:
+//
+//	./jogo validate mapa.txt maze.txt
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Deslocamentos dos quatro movimentos possíveis (WASD)
+var vizinhos = []Posicao{{0, -1}, {-1, 0}, {0, 1}, {1, 0}}
+
+// mapaValidar confere a grade, os spawns e as entidades de um mapa interpretado
+// e retorna todos os problemas encontrados: os erros, que impedem o uso do mapa,
+// e os avisos.
+func mapaValidar(m mapaLido) (erros, avisos ErrosMapa) {
+	if len(m.terreno) == 0 {
+		return ErrosMapa{{Linha: m.info.LinhaGrade, Msg: "mapa sem grade"}}, nil
+	}
+
+	// Todas as linhas da grade têm a largura declarada (ou a da primeira linha)
+	largura := len(m.terreno[0])
+	if m.info.Largura > 0 {
+		largura = m.info.Largura
+	}
+	if m.info.Altura > 0 && m.info.Altura != len(m.terreno) {
+		erros = append(erros, ErroMapa{Linha: m.linhaTamanho,
+			Msg: fmt.Sprintf("altura declarada %d, mas a grade tem %d linhas", m.info.Altura, len(m.terreno))})
+	}
+	for y, linha := range m.terreno {
+		if len(linha) != largura {
+			erros = append(erros, ErroMapa{Linha: m.info.LinhaGrade + y,
+				Msg: fmt.Sprintf("linha com largura %d; esperada %d", len(linha), largura)})
+		}
+	}
+
+	// Spawns e entidades ficam dentro do mapa, em células livres e sem se sobrepor
+	if len(m.spawns) == 0 {
+		avisos = append(avisos, ErroMapa{Linha: m.info.LinhaGrade,
+			Msg: "nenhum ponto de spawn (☺ na grade ou spawn no cabeçalho); o servidor usará a posição de -spawn"})
+	}
+	ocupadas := make(map[Posicao]string)
+	var posicionadas []Posicao
+	posicionar := func(p Posicao, nome string) {
+		switch {
+		case !m.dentro(p):
+			erros = append(erros, m.erroEm(p, fmt.Sprintf("%s em (%d, %d) fora do mapa", nome, p.X, p.Y)))
+		case m.terreno[p.Y][p.X].tangivel:
+			erros = append(erros, m.erroEm(p, fmt.Sprintf("%s em (%d, %d) sobre uma célula bloqueada (%c)",
+				nome, p.X, p.Y, m.terreno[p.Y][p.X].simbolo)))
+		case ocupadas[p] != "":
+			erros = append(erros, m.erroEm(p, fmt.Sprintf("%s em (%d, %d) na mesma célula que um %s", nome, p.X, p.Y, ocupadas[p])))
+		default:
+			ocupadas[p] = nome
+			posicionadas = append(posicionadas, p)
+		}
+	}
+	for _, p := range m.spawns {
+		posicionar(p, CelulaSpawn)
+	}
+	for _, p := range m.info.Guardas {
+		posicionar(p, TipoGuarda)
+	}
+	for _, p := range m.info.Portais {
+		posicionar(p.Posicao, TipoPortal)
+	}
+	for _, p := range m.info.Armadilhas {
+		posicionar(p, TipoArmadilha)
+	}
+	if len(posicionadas) == 0 || ocupadas[posicionadas[0]] != CelulaSpawn {
+		return erros, avisos
+	}
+
+	// Tudo o que é caminhável se alcança a partir do primeiro spawn, contando os portais
+	origem := posicionadas[0]
+	alcancadas := m.alcancaveis(origem, nil)
+	for _, p := range posicionadas[1:] {
+		if !alcancadas[p] {
+			erros = append(erros, m.erroEm(p, fmt.Sprintf("%s em (%d, %d) inalcançável a partir do spawn (%d, %d)",
+				ocupadas[p], p.X, p.Y, origem.X, origem.Y)))
+		}
+	}
+	for _, p := range m.info.Portais {
+		if p.Saida != nil && m.dentro(*p.Saida) && !m.temPassagem(*p.Saida) {
+			erros = append(erros, m.erroEm(p.Posicao, fmt.Sprintf("portal em (%d, %d) leva a (%d, %d), de onde não há passagem",
+				p.X, p.Y, p.Saida.X, p.Saida.Y)))
+		}
+	}
+	for y, linha := range m.terreno {
+		for x, e := range linha {
+			p := Posicao{x, y}
+			if e.tangivel || alcancadas[p] {
+				continue
+			}
+			// Cada região isolada é apontada uma vez, pela sua primeira célula
+			regiao := m.alcancaveis(p, alcancadas)
+			avisos = append(avisos, ErroMapa{m.info.LinhaGrade + y, x + 1,
+				fmt.Sprintf("região de %d célula(s) a partir de (%d, %d) inalcançável a partir do spawn (%d, %d)",
+					len(regiao), x, y, origem.X, origem.Y)})
+		}
+	}
+	return erros, avisos
+}
+
+// alcancaveis percorre as células caminháveis a partir de origem, atravessando os
+// portais ligados, e retorna as células alcançadas. Se marcadas não for nil, as
+// células alcançadas também são marcadas nele.
+func (m mapaLido) alcancaveis(origem Posicao, marcadas map[Posicao]bool) map[Posicao]bool {
+	saidas := make(map[Posicao]Posicao)
+	for _, p := range m.info.Portais {
+		if p.Saida != nil {
+			saidas[p.Posicao] = *p.Saida
+		}
+	}
+	alcancadas := map[Posicao]bool{origem: true}
+	fila := []Posicao{origem}
+	visitar := func(p Posicao) {
+		if m.dentro(p) && !m.terreno[p.Y][p.X].tangivel && !alcancadas[p] {
+			alcancadas[p] = true
+			fila = append(fila, p)
+		}
+	}
+	for len(fila) > 0 {
+		p := fila[0]
+		fila = fila[1:]
+		if marcadas != nil {
+			marcadas[p] = true
+		}
+		if saida, ok := saidas[p]; ok {
+			visitar(saida)
+		}
+		for _, d := range vizinhos {
+			visitar(Posicao{p.X + d.X, p.Y + d.Y})
+		}
+	}
+	return alcancadas
+}
+
+// temPassagem indica se alguma célula vizinha a p é caminhável.
+func (m mapaLido) temPassagem(p Posicao) bool {
+	for _, d := range vizinhos {
+		v := Posicao{p.X + d.X, p.Y + d.Y}
+		if m.dentro(v) && !m.terreno[v.Y][v.X].tangivel {
+			return true
+		}
+	}
+	return false
+}
+
+// dentro indica se p é uma célula da grade.
+func (m mapaLido) dentro(p Posicao) bool {
+	return p.Y >= 0 && p.Y < len(m.terreno) && p.X >= 0 && p.X < len(m.terreno[p.Y])
+}
+
+// erroEm aponta um problema na posição p: na linha do cabeçalho que a declarou
+// ou, se ela foi desenhada na grade, na linha e coluna da célula.
+func (m mapaLido) erroEm(p Posicao, msg string) ErroMapa {
+	if n, ok := m.declaradas[p]; ok {
+		return ErroMapa{Linha: n, Msg: msg}
+	}
+	return ErroMapa{m.info.LinhaGrade + p.Y, p.X + 1, msg}
+}
+
+// executarValidacao trata o subcomando "validate": confere os arquivos de mapa
+// e escreve em saida um resumo de cada mapa válido, com os seus avisos, ou todos
+// os problemas dos demais. Retorna o código de saída: 0 se todos forem válidos, 1 se algum não for
+// e 2 se nenhum arquivo for informado.
+func executarValidacao(arquivos []string, saida io.Writer) int {
+	if len(arquivos) == 0 {
+		fmt.Fprintln(saida, "uso: validate <mapa.txt>...")
+		return 2
+	}
+	codigo := 0
+	for _, arquivo := range arquivos {
+		linhas, err := jogoLerArquivoMapa(arquivo)
+		var m mapaLido
+		if err == nil {
+			m, err = mapaInterpretar(linhas)
+		}
+		var erros ErrosMapa
+		switch {
+		case errors.As(err, &erros):
+			for _, erro := range erros {
+				fmt.Fprintf(saida, "%s: %v\n", arquivo, erro)
+			}
+			codigo = 1
+		case err != nil:
+			fmt.Fprintf(saida, "%s: %v\n", arquivo, err)
+			codigo = 1
+		default:
+			for _, aviso := range m.avisos {
+				fmt.Fprintf(saida, "%s: aviso: %v\n", arquivo, aviso)
+			}
+			fmt.Fprintf(saida, "%s: ok (%dx%d, %d spawn(s), %d guarda(s), %d portal(is), %d armadilha(s))\n",
+				arquivo, len(m.terreno[0]), len(m.terreno), len(m.spawns),
+				len(m.info.Guardas), len(m.info.Portais), len(m.info.Armadilhas))
+		}
+	}
+	return codigo
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMapaValidacaoPosicaoDosErros(t *testing.T) {
+	type posicao struct{ linha, coluna int }
+	casos := []struct {
+		nome   string
+		linhas []string
+		erros  []posicao // Linha e coluna de cada erro, na ordem do relatório
+		avisos []posicao // Idem, para os avisos, que não impedem o uso do mapa
+	}{
+		{
+			nome:   "mapa válido",
+			linhas: []string{"▤▤▤▤", "▤☺ ▤", "▤▤▤▤"},
+		},
+		{
+			nome:   "sem spawn",
+			linhas: []string{"▤▤▤", "▤ ▤", "▤▤▤"},
+			avisos: []posicao{{1, 0}},
+		},
+		{
+			nome:   "símbolo fora da legenda",
+			linhas: []string{"▤▤▤▤", "▤☺x▤", "▤▤▤▤"},
+			erros:  []posicao{{2, 3}},
+		},
+		{
+			nome:   "linha de largura diferente",
+			linhas: []string{"▤▤▤▤", "▤☺ ▤", "▤▤▤"},
+			erros:  []posicao{{3, 0}},
+		},
+		{
+			nome:   "região inalcançável",
+			linhas: []string{"▤▤▤▤▤", "▤☺▤ ▤", "▤▤▤▤▤"},
+			avisos: []posicao{{2, 4}},
+		},
+		{
+			nome:   "guarda na grade sobre o spawn declarado",
+			linhas: []string{"---", "spawn: 1,1", "---", "▤▤▤▤", "▤G ▤", "▤▤▤▤"},
+			erros:  []posicao{{2, 0}},
+		},
+		{
+			nome:   "regiões isoladas após o cabeçalho",
+			linhas: []string{"---", "nome: teste", "---", "▤▤▤▤▤", "▤☺▤ ▤", "▤▤▤▤▤", "▤   ▤"},
+			avisos: []posicao{{5, 4}, {7, 2}},
+		},
+		{
+			nome:   "armadilha inalcançável",
+			linhas: []string{"---", "armadilha: 3,1", "---", "▤▤▤▤▤", "▤☺▤ ▤", "▤▤▤▤▤"},
+			erros:  []posicao{{2, 0}},
+			avisos: []posicao{{5, 4}},
+		},
+		{
+			nome:   "declarações inválidas no cabeçalho",
+			linhas: []string{"---", "tamanho: 3x5", "spawn: 9,9", "guarda 1,1", "---", "▤▤▤", "▤☺▤", "▤▤▤"},
+			erros:  []posicao{{2, 0}, {3, 0}, {4, 0}},
+		},
+		{
+			nome:   "cabeçalho sem fechamento",
+			linhas: []string{"---", "nome: teste", "▤☺▤"},
+			erros:  []posicao{{1, 0}, {3, 0}, {4, 0}}, // A grade é lida como declaração
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			m, err := mapaInterpretar(c.linhas)
+			var obtidos, avisos []posicao
+			if err != nil {
+				var erros ErrosMapa
+				if !errors.As(err, &erros) {
+					t.Fatalf("erro %T, esperado ErrosMapa: %v", err, err)
+				}
+				for _, e := range erros {
+					obtidos = append(obtidos, posicao{e.Linha, e.Coluna})
+				}
+			}
+			if !reflect.DeepEqual(obtidos, c.erros) {
+				t.Errorf("erros em %v, esperados em %v\n%v", obtidos, c.erros, err)
+			}
+			for _, e := range m.avisos {
+				avisos = append(avisos, posicao{e.Linha, e.Coluna})
+			}
+			if !reflect.DeepEqual(avisos, c.avisos) {
+				t.Errorf("avisos em %v, esperados em %v\n%v", avisos, c.avisos, m.avisos)
+			}
+		})
+	}
+}
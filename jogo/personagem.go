@@ -1,153 +1,131 @@
 package main
 
 import (
-	"fmt"
+	"strings"
 	"time"
 )
 
-// Atualiza a posição do personagem com base na tecla pressionada (WASD)
-func jogoMoverPersonagem(jogo *Jogo, dx, dy int) {
-	nx, ny := jogo.PosX+dx, jogo.PosY+dy
-	// restaura célula atual sob o jogador
-	jogo.Mapa[jogo.PosY][jogo.PosX] = jogo.UltimoVisitado
-	// captura o elemento sob o destino
-	jogo.UltimoVisitado = jogo.Mapa[ny][nx]
-	// atualiza posição do jogador
-	jogo.PosX, jogo.PosY = nx, ny
-}
-
-// Define o que ocorre quando o jogador pressiona a tecla de interação
-func personagemInteragir(jogo *Jogo) {
-	jogo.StatusMsg = fmt.Sprintf("Interagindo em (%d, %d)", jogo.PosX, jogo.PosY)
-}
-
 // personagemExecutarAcao processa o evento do teclado e envia o comando ao servidor.
+// O servidor é a fonte da verdade: o cliente envia apenas a direção e desenha o resultado.
 func personagemExecutarAcao(ev EventoTeclado, jogo *Jogo) bool {
-    // Lógica de Reinício
-    if ev.Tipo == "mover" && (ev.Tecla == 'r' || ev.Tecla == 'R') {
-        withMapaLock(func() {
-            if jogo.GameOver {
-                if err := jogoReiniciar(jogo); err != nil {
-                    jogo.StatusMsg = "Falha ao reiniciar."
-                }
-            }
-        })
-        return true
-    }
-    if clienteRPC == nil {
-        return true
-    }
-
     var comando Comando
     
     // O Sequence Number deve ser incrementado ANTES de ser usado no comando.
-    sequence++ 
-    
-    // Variáveis para armazenar o estado final e o detalhe
-    newX, newY := jogo.PosX, jogo.PosY // Inicia com a posição atual
-    acaoServidor := ""
-    detalheServidor := ""
+    // O ClientID e o token são lidos a cada tentativa, pois mudam numa reconexão
+    sequence := proximaSequencia()
     
-    // 1. Criação e Lógica do Comando RPC
+    // 1. Criação do Comando RPC
     switch ev.Tipo {
     case "sair":
-        return false
-        
-    case "mover":
-        dx, dy := 0, 0
-        switch ev.Tecla {
-        case 'w', 'W':
-            dy = -1
-        case 's', 'S':
-            dy = 1
-        case 'a', 'A':
-            dx = -1
-        case 'd', 'D':
-            dx = 1
+        // Avisa o servidor para remover o jogador imediatamente
+        comando := Comando{
+            ClientID:       clienteID(),
+            SequenceNumber: sequence,
+            Acao:           "leave",
         }
-        
-        // Posição de destino após o input
-        newX, newY = jogo.PosX+dx, jogo.PosY+dy
-        
-        // 1a. Validação de Movimento (Limites e Paredes)
-        if jogoPodeMoverPara(jogo, newX, newY) {
-            
-            // 1b. Interação com Elementos (Armadilhas e Portais)
-            elemento := jogo.Mapa[newY][newX].simbolo
-
-            if elemento == portal.Elemento.simbolo {
-                // LÓGICA DE PORTAL: Teletransporte imediato
-                destinoX, destinoY := jogoEncontrarSaida(jogo.Mapa, newX, newY)
-                withMapaLock(func() {
-                    jogo.StatusMsg = fmt.Sprintf("Entrou no portal! Teletransportado para (%d, %d)", destinoX, destinoY)
-                })
-                fmt.Printf("Portal ativado: (%d, %d) -> (%d, %d)\n", newX, newY, destinoX, destinoY)
-                newX = destinoX
-                newY = destinoY
-                acaoServidor = "update_position"
-                detalheServidor = fmt.Sprintf("X:%d,Y:%d", newX, newY)
-                
-            } else if elemento == armadilha.Elemento.simbolo {
-                // LÓGICA DE ARMADILHA: Penalidade de vida
-                jogo.Vidas-- 
-
-                withMapaLock(func() {
-                    jogo.StatusMsg = fmt.Sprintf("Caiu em armadilha! Vidas restantes: %d", jogo.Vidas)
-                })
+        var resposta Resposta
+        clienteChamar("JogoServer.ExecutarComando", comando, &resposta)
+        return false
 
-				if jogo.Vidas <= 0 {
-					jogo.GameOver = true
-					jogo.StatusMsg = "GAME OVER — Pressione R para Reiniciar"
-					// Não envia RPC de movimento se for Game Over
-					return true 
-				}
-                // O jogador permanece na armadilha na nova posição
-                acaoServidor = "update_position" 
-                detalheServidor = fmt.Sprintf("X:%d,Y:%d;VIDAS:%d", newX, newY, jogo.Vidas)
-                
-            } else {
-                // Movimento simples
-                acaoServidor = "update_position"
-                detalheServidor = fmt.Sprintf("X:%d,Y:%d", newX, newY)
+    case "mover":
+        // Lógica de Reinício
+        if ev.Tecla == 'r' || ev.Tecla == 'R' {
+            fimDeJogo := false
+            withMapaLock(func() { fimDeJogo = jogo.GameOver })
+            if !fimDeJogo {
+                return true
+            }
+            comando = Comando{
+                SequenceNumber: sequence,
+                Acao:           "restart",
             }
-            
-            // 1c. Atualiza o estado local do jogador com o resultado final da lógica
-            jogo.PosX, jogo.PosY = newX, newY 
-        } else {
-            return true 
+            break
         }
 
+        switch ev.Tecla {
+        case 'w', 'W', 'a', 'A', 's', 'S', 'd', 'D':
+        default:
+            return true
+        }
         comando = Comando{
-            ClientID:       clientID,
-            sequencenumber: sequence,
-            Acao:           acaoServidor, 
-            Detalhe:        detalheServidor, 
+            SequenceNumber: sequence,
+            Acao:           "move", 
+            Movimento:      &DadosMovimento{Direcao: string(ev.Tecla)}, 
         }
         
     case "interagir":
+        // Interage com a célula em que o personagem está
         comando = Comando{
-            ClientID:       clientID,
-            sequencenumber: sequence,
+            SequenceNumber: sequence,
             Acao:           "interact",
         }
+        withMapaLock(func() { comando.Interacao = &DadosInteracao{X: jogo.PosX, Y: jogo.PosY} })
+    case "chat":
+        if !clienteTemRecurso(RecursoChat) {
+            withMapaLock(func() {
+                jogo.StatusMsg = "Este servidor não oferece chat."
+                interfaceDesenharJogo(jogo)
+            })
+            return true
+        }
+        // Lê a mensagem na barra de status; ESC ou mensagem vazia cancelam
+        texto, ok := interfaceLerTexto(jogo, "Mensagem: ")
+        if !ok || strings.TrimSpace(texto) == "" {
+            return true
+        }
+        comando = Comando{
+            SequenceNumber: sequence,
+            Acao:           "chat",
+            Chat:           &DadosChat{Texto: texto},
+        }
+    case "pronto":
+        // Na sala de espera, alterna o estado de pronto para a rodada
+        comando = Comando{
+            SequenceNumber: sequence,
+            Acao:           "ready",
+        }
     default:
         return true
     }
 
+    // O cliente confirma o último tick recebido para receber apenas as mudanças
+    withMapaLock(func() { comando.UltimoTick = jogo.Servidor.Tick })
+
     // 2. Chamada RPC (com reexecução em caso de falha)
     var resposta Resposta
+    var err error
     for tentativas := 0; tentativas < 3; tentativas++ {
-        err := clienteRPC.Call("JogoServer.ExecutarComando", comando, &resposta)
+        // Uma reconexão entre as tentativas pode ter trocado a sessão
+        comando.ClientID = clienteID()
+        err = clienteChamar("JogoServer.ExecutarComando", comando, &resposta)
         if err == nil {
             break
         }
         time.Sleep(100 * time.Millisecond)
     }
-
-    if resposta.Sucesso {
+    if err != nil {
         withMapaLock(func() {
-            jogo.StatusMsg = resposta.Mensagem
+            jogo.StatusMsg = "Sem resposta do servidor."
         })
+        return true
     }
+
+    // 3. Desenha o que o servidor decidiu
+    withMapaLock(func() {
+        jogoAplicarResposta(jogo, resposta)
+        jogoAplicarEventos(jogo, resposta.Eventos)
+        if comando.Acao == "restart" && resposta.Ok() && jogo.Vidas > 0 {
+            if err := jogoReiniciar(jogo); err != nil {
+                jogo.StatusMsg = "Falha ao reiniciar."
+                return
+            }
+        }
+        jogo.GameOver = jogo.Vidas <= 0
+        // Recusas também são exibidas (ex.: movimento antes de a rodada começar)
+        if resposta.Mensagem != "" {
+            jogo.StatusMsg = resposta.Mensagem
+        }
+        interfaceDesenharJogo(jogo)
+    })
     return true
-}
\ No newline at end of file
+}
@@ -0,0 +1,44 @@
+// protocolo.go - Versão do protocolo e recursos negociados entre cliente e servidor
+// Cliente e servidor compartilham os tipos de Structs.go, mas podem ser compilados
+// em momentos diferentes. Todo comando leva a versão do protocolo do cliente e o
+// registro anuncia os recursos que ele sabe usar. O servidor recusa as versões
+// diferentes da sua e responde com os recursos acordados: a interseção entre
+// os pedidos e os seus. Daí em diante, os dois lados usam apenas esses recursos.
+package main
+
+// VersaoProtocolo é a versão do protocolo (tipos de Structs.go e regras de uso).
+// Deve ser incrementada sempre que um tipo trocado mudar de forma incompatível, e cada
+// incremento é uma ruptura: o servidor só atende clientes da mesma versão. Mudanças
+// que um cliente antigo pode ignorar entram como recursos, sem mudar a versão.
+//   - v3: toda inscrição no stream leva o token da sessão, como os comandos.
+//   - v4: Resposta.Mapa traz o mapa no formato de mapa.go, com o cabeçalho
+//     (pontos de spawn e pares de portais) e a legenda antes do desenho.
+const VersaoProtocolo = 4
+
+// Recursos opcionais do protocolo.
+const (
+	RecursoStream     = "stream"      // Push do estado por uma conexão de stream (server_stream.go)
+	RecursoDelta      = "delta"       // Estado em deltas; sem ele, toda resposta traz um snapshot completo
+	RecursoEventos    = "eventos"     // Lista de Eventos nas respostas
+	RecursoSalas      = "salas"       // Ações "create" e "join"
+	RecursoChat       = "chat"        // Ação "chat"
+	RecursoMapaGerado = "mapa_gerado" // Mapa novo a cada rodada, no estado (EstadoJogo.Mapa)
+)
+
+// RecursosSuportados lista os recursos que este binário implementa.
+var RecursosSuportados = []string{RecursoStream, RecursoDelta, RecursoEventos, RecursoSalas, RecursoChat, RecursoMapaGerado}
+
+// negociarRecursos retorna os recursos pedidos que também são suportados,
+// na ordem de RecursosSuportados.
+func negociarRecursos(pedidos []string) []string {
+	aceitos := make([]string, 0, len(pedidos))
+	for _, recurso := range RecursosSuportados {
+		for _, pedido := range pedidos {
+			if pedido == recurso {
+				aceitos = append(aceitos, recurso)
+				break
+			}
+		}
+	}
+	return aceitos
+}
@@ -4,24 +4,96 @@ import (
     "fmt"
     "log"
     "net"
+    "math/rand"
     "net/rpc"
+    "path/filepath"
     "sync"
+    "time"
 )
 
 // JogoServer é o tipo que implementa os métodos RPC.
 type JogoServer struct {
-    estado EstadoJogo
     mu     sync.Mutex 
-    Jogadores map[string]EstadoJogador
+    spawnX, spawnY int // Posição inicial para mapas sem pontos de spawn (☺)
+
+    // Salas hospedadas pelo servidor (server_salas.go)
+    salas    map[string]*Sala // Salas por nome
+    salaDe   map[string]*Sala // Sala em que cada jogador está
+    dirMapas string           // Diretório dos mapas que podem ser usados em novas salas
+
+    // Simulação por ticks (server_tick.go)
+    taxaTick int               // Ticks por segundo
+    tick     uint64            // Número do tick atual
+    fila     []comandoPendente // Comandos recebidos aguardando o próximo tick
+    rng      *rand.Rand        // Fonte aleatória da simulação, semeada para reprodutibilidade
+
+    // Sessões (server_sessao.go)
+    contagemEspera time.Duration    // Espera máxima após o primeiro jogador pronto (server_espera.go)
+
+    timeoutSessao time.Duration     // Tempo sem contato até o jogador ser removido
+    ultimoContato map[string]uint64 // Tick do último contato de cada jogador
+    tokens        map[string]string // Token da sessão -> ClientID
+    tokensPorID   map[string]string // ClientID -> token da sessão
+    publicos      map[string]string // ClientID -> identificador público, o único publicado no estado
+    recursos      map[string]map[string]bool // Recursos do protocolo acordados com cada jogador (protocolo.go)
+    ordemRegistro map[string]uint64 // Ordem de registro de cada jogador, base da ordem no tick (server_colisao.go)
+    registros     uint64            // Registros já feitos, para numerar o próximo
+
+    // Respostas já produzidas por cliente, para execução única (server_respostas.go)
+    respostas map[string]*cacheRespostas
+
+    // Clientes inscritos no stream de estado (server_stream.go)
+    assinantes map[*assinante]struct{}
 }
 
-// NovoJogoServer inicializa o servidor de jogo.
-func NovoJogoServer() *JogoServer {
-    return &JogoServer{
-        estado: EstadoJogo{
-            Jogadores: make(map[string]EstadoJogador),
-        },
+// NovoJogoServer inicializa o servidor de jogo a partir da configuração:
+// cria a sala principal com o mapa (lido ou gerado), define a taxa de ticks, a semente da
+// simulação, a posição inicial dos jogadores e o timeout das sessões.
+func NovoJogoServer(cfg Config) (*JogoServer, error) {
+    taxaTick := cfg.TaxaTick
+    if taxaTick <= 0 {
+        taxaTick = TaxaTickPadrao
     }
+    timeoutSessao := cfg.TimeoutSessao
+    if timeoutSessao <= 0 {
+        timeoutSessao = TimeoutSessaoPadrao
+    }
+    contagemEspera := cfg.ContagemEspera
+    if contagemEspera <= 0 {
+        contagemEspera = ContagemEsperaPadrao
+    }
+    s := &JogoServer{
+        salas:    make(map[string]*Sala),
+        salaDe:   make(map[string]*Sala),
+        dirMapas: filepath.Dir(cfg.Mapa),
+        taxaTick: taxaTick,
+        rng:      rand.New(rand.NewSource(cfg.Semente)),
+        spawnX:   cfg.SpawnX,
+        spawnY:   cfg.SpawnY,
+        assinantes: make(map[*assinante]struct{}),
+        respostas:  make(map[string]*cacheRespostas),
+        timeoutSessao: timeoutSessao,
+        contagemEspera: contagemEspera,
+        ultimoContato: make(map[string]uint64),
+        tokens:        make(map[string]string),
+        tokensPorID:   make(map[string]string),
+        publicos:      make(map[string]string),
+        recursos:      make(map[string]map[string]bool),
+        ordemRegistro: make(map[string]uint64),
+    }
+    // Com -gerar, a sala principal ganha um mapa gerado a cada rodada
+    var principal *Sala
+    var err error
+    if cfg.Gerador != "" {
+        principal, err = s.novaSalaGerada(SalaPrincipal, cfg.Gerador)
+    } else {
+        principal, err = s.novaSala(SalaPrincipal, cfg.Mapa)
+    }
+    if err != nil {
+        return nil, err
+    }
+    principal.permanente = true
+    return s, nil
 }
 
 // Implementação do RPC: BuscarEstado
@@ -30,96 +102,271 @@ func (s *JogoServer) BuscarEstado(comando *Comando, resposta *Resposta) error {
     defer s.mu.Unlock()
 
     fmt.Printf("[Servidor] REQ: %s, Cliente: %s\n", comando.Acao, comando.ClientID)
-    *resposta = Resposta{
-        Sucesso:  true,
-        Mensagem: "Estado atual enviado.",
-        EstadoAtual: s.estado,
+    if falha := recusaVersao(comando.Versao); falha != nil {
+        *resposta = *falha
+        return nil
+    }
+    if falha := s.recusaToken(comando); falha != nil {
+        *resposta = *falha
+        return nil
+    }
+    s.registrarContato(comando.ClientID) // Cada consulta funciona como heartbeat
+    sala, ok := s.salaDe[comando.ClientID]
+    if !ok {
+        *resposta = respostaFalha(CodigoNaoRegistrado, MotivoNaoRegistrado, "Jogador não registrado.")
+        return nil
     }
+    *resposta = respostaOK("")
+    resposta.Delta = s.estadoPara(comando.ClientID, sala, comando.UltimoTick)
     return nil
 }
 
 // Implementação do RPC: ExecutarComando
+// O comando é enfileirado e aplicado no próximo tick da simulação;
+// a chamada retorna quando o tick que o processou termina.
 func (s *JogoServer) ExecutarComando(comando *Comando, resposta *Resposta) error {
+    fmt.Printf("[Servidor] REQ: %s, Cliente: %s, Seq: %d\n", 
+        comando.Acao, comando.ClientID, comando.SequenceNumber)
+
+    pronto := make(chan Resposta, 1)
+    pendente := s.novoPendente(*comando, pronto)
     s.mu.Lock()
-    defer s.mu.Unlock()
+    s.fila = append(s.fila, pendente)
+    s.mu.Unlock()
 
-    fmt.Printf("[Servidor] REQ: %s, Cliente: %s, Seq: %d, Detalhe: %s\n", 
-        comando.Acao, comando.ClientID, comando.sequencenumber, comando.Detalhe)
-
-    jogador, existe := s.estado.Jogadores[comando.ClientID]
-    
-    // 1. Garantia de Execução Única (Exactly-Once)
-    if existe && comando.sequencenumber <= jogador.UltimoComando {
-        *resposta = Resposta{
-            Sucesso:  true,
-            Mensagem: "Comando já processado (retransmissão detectada).",
-            EstadoAtual: s.estado,
-        }
-        return nil 
+    *resposta = <-pronto
+    return nil
+}
+
+// aplicarComando executa um comando sobre o estado do jogo.
+// Retransmissões já foram filtradas pelo cache de respostas (server_respostas.go)
+// e comandos sem o token da sessão, recusados (server_sessao.go).
+// Deve ser chamada com s.mu travado, dentro de um tick.
+func (s *JogoServer) aplicarComando(pendente *comandoPendente) Resposta {
+    comando := &pendente.comando
+    // Versão, ação e dados do comando são conferidos antes de tocar no estado (server_comandos.go)
+    if falha := validarComando(comando); falha != nil {
+        return *falha
     }
 
-    mensagemServidor := ""
+    sala, existe := s.salaDe[comando.ClientID]
+    if !existe && comando.Acao != "register" && comando.Acao != "rooms" {
+        return respostaFalha(CodigoNaoRegistrado, MotivoNaoRegistrado, "Jogador não registrado.")
+    }
 
-    // 2. Execução do Comando e Atualização do Estado
+    // Ações opcionais exigem o recurso acordado no registro (protocolo.go)
+    if recurso, ok := recursoDaAcao[comando.Acao]; ok && !s.temRecurso(comando.ClientID, recurso) {
+        return respostaFalha(CodigoRecusado, MotivoRecursoNaoAcordado,
+            fmt.Sprintf("A ação %q exige o recurso %q, não acordado no registro.", comando.Acao, recurso))
+    }
+
+    var resposta Resposta
+    var jogador EstadoJogador
+    if existe {
+        jogador = sala.estado.Jogadores[comando.ClientID]
+    }
+    // Na sala de espera só valem os comandos de sala (server_espera.go)
+    emEspera := existe && sala.estado.Fase != FaseJogo
+
+    // Execução do Comando e Atualização do Estado
     switch comando.Acao {
     case "register":
+        // Nome, identificador e token de retomada são tratados em server_sessao.go
+        return s.registrarJogador(comando)
+        
+    case "rooms":
+        // A lista de salas pode ser pedida antes do registro
+        resposta = respostaOK("")
+        resposta.Salas = s.listarSalas()
+        resposta.Mensagem = fmt.Sprintf("%d sala(s) disponível(is).", len(resposta.Salas))
         if !existe {
-            s.estado.Jogadores[comando.ClientID] = EstadoJogador{
-                X: 3, 
-                Y: 3,
-                Vidas: 3,
-                UltimoComando: comando.sequencenumber,
-            }
-            mensagemServidor = "Jogador registrado com sucesso."
+            return resposta
         }
-        
-    case "update_position":
-        var newX, newY, newVidas int
-        
-        // Tenta ler o formato que inclui Vidas (usado após cair em armadilha, por exemplo)
-        // O cliente envia: "X:%d,Y:%d;VIDAS:%d"
-        _, errVidas := fmt.Sscanf(comando.Detalhe, "X:%d,Y:%d;VIDAS:%d", &newX, &newY, &newVidas)
-
-        if errVidas == nil {
-            // Sucesso na leitura de posição e vidas
-            jogador.Vidas = newVidas
-            jogador.X = newX
-            jogador.Y = newY
-            mensagemServidor = fmt.Sprintf("Posição e Vidas atualizadas: X=%d, Y=%d, Vidas=%d", newX, newY, newVidas)
-            
-        } else {
-            if _, errPos := fmt.Sscanf(comando.Detalhe, "X:%d,Y:%d", &newX, &newY); errPos == nil {
-                jogador.X = newX
-                jogador.Y = newY
-                mensagemServidor = fmt.Sprintf("Posição atualizada: X=%d, Y=%d", newX, newY)
-            } else {
-                mensagemServidor = "Erro de formato no detalhe da posição. Posição não atualizada."
-                break 
+
+    case "create":
+        resposta = s.criarSala(comando.ClientID, *comando.Sala, pendente.mapa)
+
+    case "join":
+        resposta = s.entrarSala(comando.ClientID, comando.Sala.Nome)
+
+    case "ready":
+        resposta = sala.marcarPronto(&jogador)
+
+    case "move":
+        if emEspera {
+            resposta = respostaFalha(CodigoRecusado, MotivoRodadaNaoIniciada, mensagemEspera)
+            break
+        }
+        // O cliente envia apenas a direção ("w", "a", "s" ou "d");
+        // o servidor decide a nova posição e a perda de vidas.
+        resposta = sala.moverJogador(comando.ClientID, &jogador, comando.Movimento.Direcao)
+
+    case "restart":
+        if emEspera {
+            resposta = respostaFalha(CodigoRecusado, MotivoRodadaNaoIniciada, mensagemEspera)
+            break
+        }
+        resposta = respostaOK("")
+        if jogador.Vidas <= 0 {
+            x, y, ok := sala.escolherSpawn()
+            if !ok {
+                resposta = recusaSalaCheia(sala)
+                break
             }
+            jogador.X, jogador.Y = x, y
+            jogador.Vidas = 3
+            resposta.Mensagem = "Jogo reiniciado."
         }
         
-        jogador.UltimoComando = comando.sequencenumber
-        s.estado.Jogadores[comando.ClientID] = jogador
-        
     case "interact":
-        jogador.UltimoComando = comando.sequencenumber
-        s.estado.Jogadores[comando.ClientID] = jogador
-        mensagemServidor = "Interação registrada."
-        
+        if emEspera {
+            resposta = respostaFalha(CodigoRecusado, MotivoRodadaNaoIniciada, mensagemEspera)
+            break
+        }
+        resposta = sala.interagir(jogador, *comando.Interacao)
+
+    case "chat":
+        // O chat vale também na sala de espera
+        sala.enviarChat(comando.ClientID, jogador, comando.Chat.Texto)
+        resposta = respostaOK("")
+
+    case "leave":
+        s.removerJogador(comando.ClientID, "saiu do jogo")
+        return respostaOK("Até logo!")
+
     }
 
-    // 3. Resposta final para o cliente
-    *resposta = Resposta{
-        Sucesso:  true,
-        Mensagem: mensagemServidor,
-        EstadoAtual: s.estado,
+    // "create" e "join" podem ter levado o jogador para outra sala
+    if atual := s.salaDe[comando.ClientID]; atual != sala {
+        sala = atual
+        jogador = sala.estado.Jogadores[comando.ClientID]
     }
-    return nil
+
+    // Todo comando processado avança o último comando do jogador, mesmo quando falha
+    jogador.UltimoComando = comando.SequenceNumber
+    sala.definirJogador(comando.ClientID, jogador)
+    s.registrarContato(comando.ClientID)
+
+    // O estado e os eventos do tick são anexados ao final do tick
+    return resposta
 }
+
+// direcaoParaDelta converte a tecla de direção enviada pelo cliente em deslocamento.
+func direcaoParaDelta(direcao string) (dx, dy int, ok bool) {
+    switch direcao {
+    case "w", "W":
+        return 0, -1, true
+    case "s", "S":
+        return 0, 1, true
+    case "a", "A":
+        return -1, 0, true
+    case "d", "D":
+        return 1, 0, true
+    }
+    return 0, 0, false
+}
+
+// moverJogador aplica as regras do mapa da sala ao movimento pedido e atualiza o jogador.
+// Os acontecimentos (inimigo, portal, armadilha, fim de jogo) viram eventos da sala.
+func (sala *Sala) moverJogador(clientID string, jogador *EstadoJogador, direcao string) Resposta {
+    if jogador.Vidas <= 0 {
+        return respostaFalha(CodigoRecusado, MotivoFimDeJogo, "GAME OVER — Pressione R para Reiniciar")
+    }
+    dx, dy, ok := direcaoParaDelta(direcao)
+    if !ok {
+        return respostaFalha(CodigoInvalido, MotivoDirecaoInvalida, "Erro de formato na direção do movimento. Posição não atualizada.")
+    }
+
+    nx, ny := jogador.X+dx, jogador.Y+dy
+    if ny < 0 || ny >= len(sala.mapa.Mapa.Terreno) || nx < 0 || nx >= len(sala.mapa.Mapa.Terreno[ny]) {
+        return respostaFalha(CodigoRecusado, MotivoForaDoMapa, "Movimento bloqueado.")
+    }
+
+    // Encostar em um inimigo custa uma vida; o jogador permanece onde está
+    if sala.mapa.Mapa.Terreno[ny][nx].simbolo == Inimigo.simbolo {
+        sala.registrarEvento(EventoInimigo, clientID, nx, ny)
+        if sala.perderVida(clientID, jogador) {
+            return respostaFalha(CodigoRecusado, MotivoFimDeJogo, "GAME OVER — Pressione R para Reiniciar")
+        }
+        return respostaFalha(CodigoRecusado, MotivoInimigo, fmt.Sprintf("Atingido por um inimigo! Vidas restantes: %d", jogador.Vidas))
+    }
+
+    if !jogoPodeMoverPara(&sala.mapa, nx, ny) {
+        return respostaFalha(CodigoRecusado, MotivoParede, "Movimento bloqueado.")
+    }
+
+    // Dois jogadores nunca ocupam a mesma célula, e quem sai dela neste tick ainda
+    // a ocupa até o fim dele (server_colisao.go)
+    if ocupante, ok := sala.ocupanteNoTick(nx, ny); ok && ocupante.clientID != clientID {
+        return sala.recusaOcupada(ocupante, nx, ny)
+    }
+
+    // Interação com os elementos autônomos compartilhados
+    if id, entidade, ok := sala.entidadeEm(nx, ny); ok {
+        switch entidade.Tipo {
+        case TipoGuarda:
+            return respostaFalha(CodigoRecusado, MotivoGuarda, "O guarda bloqueia o caminho.")
+        case TipoPortal:
+            // LÓGICA DE PORTAL: Teletransporte imediato para o portal ligado ou para uma célula sorteada
+            destinoX, destinoY, ok := sala.saidaPortal(id, nx, ny)
+            if !ok {
+                return respostaFalha(CodigoRecusado, MotivoSemCelulaLivre, "O portal não tem saída livre.")
+            }
+            jogador.X, jogador.Y = destinoX, destinoY
+            sala.registrarEvento(EventoPortal, clientID, destinoX, destinoY)
+            return respostaOK(fmt.Sprintf("Entrou no portal! Teletransportado para (%d, %d)", destinoX, destinoY))
+        case TipoArmadilha:
+            // LÓGICA DE ARMADILHA: Penalidade de vida; o jogador permanece na armadilha
+            jogador.X, jogador.Y = nx, ny
+            sala.registrarEvento(EventoArmadilha, clientID, nx, ny)
+            if sala.perderVida(clientID, jogador) {
+                return respostaOK("GAME OVER — Pressione R para Reiniciar")
+            }
+            return respostaOK(fmt.Sprintf("Caiu em armadilha! Vidas restantes: %d", jogador.Vidas))
+        }
+    }
+
+    jogador.X, jogador.Y = nx, ny
+    return respostaOK("")
+}
+
+// interagir trata a ação "interact" sobre a célula alvo, que deve ser a do
+// jogador ou uma vizinha.
+func (sala *Sala) interagir(jogador EstadoJogador, alvo DadosInteracao) Resposta {
+    dx, dy := alvo.X-jogador.X, alvo.Y-jogador.Y
+    if dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+        return respostaFalha(CodigoRecusado, MotivoAlvoInvalido, "Alvo fora de alcance.")
+    }
+    if alvo.Y < 0 || alvo.Y >= len(sala.mapa.Mapa.Terreno) || alvo.X < 0 || alvo.X >= len(sala.mapa.Mapa.Terreno[alvo.Y]) {
+        return respostaFalha(CodigoRecusado, MotivoForaDoMapa, "Alvo fora do mapa.")
+    }
+    if _, entidade, ok := sala.entidadeEm(alvo.X, alvo.Y); ok {
+        return respostaOK(fmt.Sprintf("Interagindo com %s em (%d, %d).", entidade.Tipo, alvo.X, alvo.Y))
+    }
+    return respostaOK(fmt.Sprintf("Interagindo em (%d, %d).", alvo.X, alvo.Y))
+}
+
+// perderVida tira uma vida do jogador e registra o fim de jogo se elas acabarem.
+// Retorna true se o jogador ficou sem vidas.
+func (sala *Sala) perderVida(clientID string, jogador *EstadoJogador) bool {
+    jogador.Vidas--
+    if jogador.Vidas > 0 {
+        return false
+    }
+    sala.registrarEvento(EventoFimDeJogo, clientID, jogador.X, jogador.Y)
+    return true
+}
+
 // Inicia o Servidor RPC
-func IniciarServidor(porta string) {
-    servidor := NovoJogoServer()
-    rpc.Register(servidor)
+func IniciarServidor(cfg Config) {
+    porta := cfg.Porta
+    servidor, err := NovoJogoServer(cfg)
+    if err != nil {
+        log.Fatal("Erro ao carregar o mapa:", err)
+    }
+    go servidor.loopTicks()
+    servidorRPC := rpc.NewServer()
+    servidorRPC.Register(servidor)
 
     listener, err := net.Listen("tcp", ":"+porta)
     if err != nil {
@@ -127,5 +374,16 @@ func IniciarServidor(porta string) {
     }
     log.Println("Servidor RPC iniciado na porta:", porta)
 
-    rpc.Accept(listener)
-}
\ No newline at end of file
+    // Gateway JSON (JSON-RPC, WebSocket e stream em JSON) para clientes em outras linguagens
+    if cfg.PortaGateway != "" {
+        listenerGateway, err := net.Listen("tcp", ":"+cfg.PortaGateway)
+        if err != nil {
+            log.Fatal("Erro ao iniciar listener do gateway:", err)
+        }
+        log.Println("Gateway JSON iniciado na porta:", cfg.PortaGateway)
+        go servidor.aceitarGateway(listenerGateway, servidorRPC)
+    }
+
+    // RPC e stream de estado compartilham o mesmo listener
+    servidor.aceitarConexoes(listener, servidorRPC)
+}
@@ -0,0 +1,128 @@
+// server_colisao.go - Grade de ocupação para colisão entre jogadores
+// Cada sala mantém uma grade com o jogador que ocupa cada célula. Toda mudança de
+// posição passa por definirJogador/retirarJogador, de modo que dois jogadores
+// nunca ocupam a mesma célula. Os movimentos são resolvidos contra as posições do
+// início do tick: a célula que o ocupante deixa durante o tick só fica livre no
+// próximo. Movimentos simultâneos para a mesma célula livre são resolvidos pela
+// ordem dos comandos no tick (ordemNoTick): o primeiro ocupa a célula e os demais
+// têm o movimento recusado.
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// celulaOcupada registra quem está numa célula e desde qual tick.
+type celulaOcupada struct {
+	clientID string
+	desde    uint64
+	saiu     bool // O jogador deixou a célula no tick "saida"
+	saida    uint64
+}
+
+// novaOcupacao cria a grade vazia com as dimensões do mapa da sala.
+func (sala *Sala) novaOcupacao() {
+	sala.ocupacao = make([][]celulaOcupada, len(sala.mapa.Mapa.Terreno))
+	for y, linha := range sala.mapa.Mapa.Terreno {
+		sala.ocupacao[y] = make([]celulaOcupada, len(linha))
+	}
+}
+
+// ocupante retorna o jogador na célula (x, y), se houver.
+func (sala *Sala) ocupante(x, y int) (celulaOcupada, bool) {
+	if y < 0 || y >= len(sala.ocupacao) || x < 0 || x >= len(sala.ocupacao[y]) {
+		return celulaOcupada{}, false
+	}
+	c := sala.ocupacao[y][x]
+	return c, c.clientID != "" && !c.saiu
+}
+
+// ocupanteNoTick retorna quem impede a entrada na célula (x, y) neste tick: o
+// jogador que está nela ou o que estava nela no início do tick e saiu durante ele.
+func (sala *Sala) ocupanteNoTick(x, y int) (celulaOcupada, bool) {
+	if y < 0 || y >= len(sala.ocupacao) || x < 0 || x >= len(sala.ocupacao[y]) {
+		return celulaOcupada{}, false
+	}
+	c := sala.ocupacao[y][x]
+	if c.saiu {
+		tick := sala.servidor.tick
+		return c, c.saida == tick && c.desde < tick
+	}
+	return c, c.clientID != ""
+}
+
+// definirJogador grava o estado do jogador e atualiza a grade se ele mudou de célula.
+// Posições fora do mapa (ex.: -1, -1) tiram o jogador da grade.
+func (sala *Sala) definirJogador(clientID string, jogador EstadoJogador) {
+	anterior, existia := sala.estado.Jogadores[clientID]
+	sala.estado.Jogadores[clientID] = jogador
+	if existia && anterior.X == jogador.X && anterior.Y == jogador.Y {
+		return
+	}
+	if existia {
+		sala.liberarCelula(clientID, anterior.X, anterior.Y)
+	}
+	if y, x := jogador.Y, jogador.X; y >= 0 && y < len(sala.ocupacao) && x >= 0 && x < len(sala.ocupacao[y]) {
+		sala.ocupacao[y][x] = celulaOcupada{clientID: clientID, desde: sala.servidor.tick}
+	}
+}
+
+// retirarJogador remove o jogador da sala e esvazia sua célula de imediato.
+func (sala *Sala) retirarJogador(clientID string) {
+	if jogador, ok := sala.estado.Jogadores[clientID]; ok {
+		if c, ok := sala.ocupante(jogador.X, jogador.Y); ok && c.clientID == clientID {
+			sala.ocupacao[jogador.Y][jogador.X] = celulaOcupada{}
+		}
+		delete(sala.estado.Jogadores, clientID)
+	}
+}
+
+// liberarCelula marca a saída do jogador da célula, se ela ainda for dele. Até o fim
+// do tick, ocupanteNoTick ainda o aponta nela.
+func (sala *Sala) liberarCelula(clientID string, x, y int) {
+	if c, ok := sala.ocupante(x, y); ok && c.clientID == clientID {
+		sala.ocupacao[y][x].saiu, sala.ocupacao[y][x].saida = true, sala.servidor.tick
+	}
+}
+
+// recusaOcupada explica por que o jogador não pode entrar numa célula ocupada.
+func (sala *Sala) recusaOcupada(c celulaOcupada, x, y int) Resposta {
+	nome := sala.estado.Jogadores[c.clientID].Nome
+	if c.desde == sala.servidor.tick && !c.saiu {
+		return respostaFalha(CodigoRecusado, MotivoMovimentoSimultaneo,
+			fmt.Sprintf("Movimento simultâneo: %s chegou antes a (%d, %d).", nome, x, y))
+	}
+	return respostaFalha(CodigoRecusado, MotivoCelulaOcupada,
+		fmt.Sprintf("Posição (%d, %d) ocupada por %s.", x, y, nome))
+}
+
+// ordemNoTick define a ordem dos clientes dentro de um tick. Os clientes da fila,
+// na ordem em que se registraram, são embaralhados pela fonte aleatória do servidor,
+// semeada com -semente: com a mesma semente e os mesmos comandos a ordem se repete,
+// mas muda a cada tick, para que nenhum jogador vença sempre os conflitos de
+// movimento. Os comandos sem sessão ficam de fora e vão para o fim da fila.
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) ordemNoTick(fila []comandoPendente) map[string]int {
+	var clientes []string
+	ordem := make(map[string]int)
+	for _, pendente := range fila {
+		id := pendente.comando.ClientID
+		if _, ok := s.ordemRegistro[id]; ok {
+			if _, visto := ordem[id]; !visto {
+				ordem[id] = 0
+				clientes = append(clientes, id)
+			}
+		}
+	}
+	sort.Slice(clientes, func(i, j int) bool {
+		return s.ordemRegistro[clientes[i]] < s.ordemRegistro[clientes[j]]
+	})
+	s.rng.Shuffle(len(clientes), func(i, j int) {
+		clientes[i], clientes[j] = clientes[j], clientes[i]
+	})
+	for i, id := range clientes {
+		ordem[id] = i
+	}
+	return ordem
+}
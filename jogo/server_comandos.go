@@ -0,0 +1,139 @@
+// server_comandos.go - Validação do esquema dos comandos recebidos
+// Cada ação aceita pelo servidor declara quais dados (Comando.Registro, Movimento,
+// Interacao, Chat ou Sala) ela exige. Antes de chegar ao estado do jogo, o comando
+// passa por validarComando: versão do protocolo (protocolo.go), ação conhecida,
+// presença apenas dos dados da ação e conteúdo desses dados. Comandos fora do
+// esquema recebem CodigoInvalido (ou CodigoIncompativel) com o motivo, em vez de
+// serem ignorados.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Tamanho máximo, em caracteres, de uma mensagem de chat
+const maxTamanhoChat = 120
+
+// Tamanhos mínimo e máximo, em bytes, do nonce do registro. O mínimo corresponde a
+// 16 bytes aleatórios em hexadecimal, como o nonce do cliente (conexao.go): um nonce
+// curto poderia ser adivinhado ou coincidir com o de outro cliente.
+const (
+	minTamanhoNonce = 32
+	maxTamanhoNonce = 64
+)
+
+// dadosComando identifica os campos Dados* de Comando.
+type dadosComando int
+
+const (
+	semDados dadosComando = iota
+	dadosRegistro
+	dadosMovimento
+	dadosInteracao
+	dadosChat
+	dadosSala
+)
+
+// acoesComando lista as ações aceitas por ExecutarComando e os dados que cada uma exige.
+var acoesComando = map[string]dadosComando{
+	"register": dadosRegistro,
+	"rooms":    semDados,
+	"create":   dadosSala,
+	"join":     dadosSala,
+	"ready":    semDados,
+	"move":     dadosMovimento,
+	"restart":  semDados,
+	"interact": dadosInteracao,
+	"chat":     dadosChat,
+	"leave":    semDados,
+}
+
+// recursoDaAcao indica as ações que só podem ser usadas por clientes que acordaram um recurso.
+var recursoDaAcao = map[string]string{
+	"create": RecursoSalas,
+	"join":   RecursoSalas,
+	"chat":   RecursoChat,
+}
+
+// recusaVersao retorna a recusa para clientes com outra versão de protocolo, ou nil.
+func recusaVersao(versao int) *Resposta {
+	if versao == VersaoProtocolo {
+		return nil
+	}
+	r := respostaFalha(CodigoIncompativel, MotivoProtocoloIncompativel,
+		fmt.Sprintf("Cliente com protocolo v%d; o servidor usa a v%d. Atualize o cliente.",
+			versao, VersaoProtocolo))
+	return &r
+}
+
+// validarComando confere o comando contra o esquema. Retorna a resposta de
+// recusa, ou nil se o comando for válido. Não depende do estado do jogo.
+func validarComando(comando *Comando) *Resposta {
+	recusa := func(motivo, mensagem string) *Resposta {
+		r := respostaFalha(CodigoInvalido, motivo, mensagem)
+		return &r
+	}
+	if falha := recusaVersao(comando.Versao); falha != nil {
+		return falha
+	}
+	esperado, ok := acoesComando[comando.Acao]
+	if !ok {
+		return recusa(MotivoAcaoDesconhecida, fmt.Sprintf("Ação desconhecida: %q.", comando.Acao))
+	}
+
+	// Apenas os dados da própria ação podem estar presentes
+	presentes := map[dadosComando]bool{
+		dadosRegistro:  comando.Registro != nil,
+		dadosMovimento: comando.Movimento != nil,
+		dadosInteracao: comando.Interacao != nil,
+		dadosChat:      comando.Chat != nil,
+		dadosSala:      comando.Sala != nil,
+	}
+	for dados, presente := range presentes {
+		if presente != (dados == esperado) {
+			return recusa(MotivoFormatoInvalido, fmt.Sprintf("Dados inválidos para a ação %q.", comando.Acao))
+		}
+	}
+
+	switch comando.Acao {
+	case "register":
+		if n := len(comando.Registro.Nonce); n > 0 && (n < minTamanhoNonce || n > maxTamanhoNonce) {
+			return recusa(MotivoFormatoInvalido, fmt.Sprintf("Nonce do registro deve ter de %d a %d bytes.", minTamanhoNonce, maxTamanhoNonce))
+		}
+	case "move":
+		if _, _, ok := direcaoParaDelta(comando.Movimento.Direcao); !ok {
+			return recusa(MotivoDirecaoInvalida, fmt.Sprintf("Direção inválida: %q.", comando.Movimento.Direcao))
+		}
+	case "create":
+		if comando.Sala.Nome == "" || comando.Sala.Mapa == "" {
+			return recusa(MotivoFormatoInvalido, "Informe o nome da sala e o mapa.")
+		}
+	case "join":
+		if comando.Sala.Nome == "" {
+			return recusa(MotivoFormatoInvalido, "Informe o nome da sala.")
+		}
+	case "chat":
+		texto, ok := normalizarChat(comando.Chat.Texto)
+		if !ok {
+			return recusa(MotivoTextoInvalido,
+				fmt.Sprintf("Mensagem vazia ou longa demais (máximo de %d caracteres).", maxTamanhoChat))
+		}
+		comando.Chat.Texto = texto
+	}
+	return nil
+}
+
+// normalizarChat remove espaços nas pontas e troca caracteres de controle por espaços.
+// Retorna false se a mensagem ficar vazia ou passar de maxTamanhoChat caracteres.
+func normalizarChat(texto string) (string, bool) {
+	texto = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return ' '
+		}
+		return r
+	}, texto))
+	n := len([]rune(texto))
+	return texto, n > 0 && n <= maxTamanhoChat
+}
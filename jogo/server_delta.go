@@ -0,0 +1,45 @@
+// server_delta.go - Histórico de estados por tick usado para responder com deltas
+// Cada sala guarda os estados publicados ao final de cada tick por um tempo limitado;
+// um cliente que confirma ter recebido o tick N recebe apenas o que mudou desde N.
+package main
+
+// Quantidade de estados guardados; clientes que confirmam um tick anterior ao
+// mais antigo do histórico estão atrasados demais e recebem snapshot completo.
+const maxHistoricoEstados = 64
+
+// registrarHistorico guarda o estado atual no histórico.
+func (sala *Sala) registrarHistorico() {
+	sala.historico = append(sala.historico, sala.copiaEstado())
+	if len(sala.historico) > maxHistoricoEstados {
+		sala.historico = sala.historico[len(sala.historico)-maxHistoricoEstados:]
+	}
+}
+
+// estadoNoTick retorna o estado como ele era ao final do tick informado,
+// se ele ainda puder ser reconstruído pelo histórico.
+func (sala *Sala) estadoNoTick(tick uint64) (EstadoJogo, bool) {
+	if tick == 0 || tick > sala.servidor.tick || len(sala.historico) == 0 || tick < sala.historico[0].Tick {
+		return EstadoJogo{}, false
+	}
+	// O histórico só guarda ticks que mudaram o estado:
+	// o estado no tick pedido é o último registrado até ele.
+	for i := len(sala.historico) - 1; i >= 0; i-- {
+		if sala.historico[i].Tick <= tick {
+			return sala.historico[i], true
+		}
+	}
+	return EstadoJogo{}, false
+}
+
+// deltaDesde calcula o delta entre o tick confirmado pelo cliente e o estado atual,
+// ou um snapshot completo se o tick não estiver mais disponível.
+func (sala *Sala) deltaDesde(tick uint64) DeltaEstado {
+	atual := sala.copiaEstado()
+	base, ok := sala.estadoNoTick(tick)
+	if !ok {
+		return snapshotCompleto(atual)
+	}
+	delta := deltaEntre(base, atual)
+	delta.TickBase = tick
+	return delta
+}
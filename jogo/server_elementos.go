@@ -0,0 +1,222 @@
+// server_elementos.go - Guardas, portais e armadilhas simulados pelo servidor
+// Os elementos autônomos pertencem a cada sala e suas posições são publicadas
+// em EstadoJogo.Entidades, para que todos os jogadores da sala vejam o mesmo mundo.
+// Eles avançam a cada tick da simulação (server_tick.go).
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Identificadores das entidades autônomas no mapa EstadoJogo.Entidades
+const (
+	idGuarda    = "guarda"
+	idPortal    = "portal"
+	idArmadilha = "armadilha"
+)
+
+// Distância (em células) a partir da qual o guarda passa a perseguir um jogador
+const distanciaPerseguicao = 8
+
+// Intervalos entre os movimentos dos elementos autônomos
+const (
+	intervaloGuardaMoveu  = 300 * time.Millisecond
+	intervaloGuardaParado = 120 * time.Millisecond
+	intervaloPortal       = 15 * time.Second
+	intervaloArmadilha    = 10 * time.Second
+)
+
+// iniciarElementos posiciona os elementos autônomos e agenda seus movimentos.
+// Se o mapa declara guardas, portais ou armadilhas (mapa.go), eles começam nas
+// posições declaradas; portais e armadilhas ficam fixos e cada portal de um par
+// leva ao outro. Sem declarações, a sala tem um guarda, um portal e uma armadilha
+// em posições sorteadas, e o portal e a armadilha mudam de lugar periodicamente.
+func (sala *Sala) iniciarElementos() {
+	sala.proximoMovimento = make(map[string]uint64)
+	sala.saidasPortal = make(map[string]Posicao)
+	info := sala.mapa.Info
+	if len(info.Guardas)+len(info.Portais)+len(info.Armadilhas) == 0 {
+		// Sem célula livre (mapa lotado), a entidade fica de fora da rodada
+		posicionar := func(id, tipo string, x, y int, ok bool, primeiroMovimento uint64) {
+			if ok {
+				sala.estado.Entidades[id] = EstadoEntidade{Tipo: tipo, X: x, Y: y}
+				sala.proximoMovimento[id] = primeiroMovimento
+			}
+		}
+		tick := sala.servidor.tick
+		gx, gy, ok := 2, 2, true
+		if !sala.celulaLivre(gx, gy) {
+			gx, gy, ok = sala.posicaoAleatoriaLivre(gx, gy)
+		}
+		posicionar(idGuarda, TipoGuarda, gx, gy, ok, tick)
+		px, py, ok := sala.posicaoAleatoriaLivre(5, 5)
+		posicionar(idPortal, TipoPortal, px, py, ok, tick+sala.servidor.ticksPara(intervaloPortal))
+		ax, ay, ok := sala.posicaoAleatoriaLivre(6, 6)
+		posicionar(idArmadilha, TipoArmadilha, ax, ay, ok, tick+sala.servidor.ticksPara(intervaloArmadilha))
+		return
+	}
+
+	for i, p := range info.Guardas {
+		id := idEntidade(idGuarda, i)
+		sala.estado.Entidades[id] = EstadoEntidade{Tipo: TipoGuarda, X: p.X, Y: p.Y}
+		sala.proximoMovimento[id] = sala.servidor.tick
+	}
+	for i, p := range info.Portais {
+		id := idEntidade(idPortal, i)
+		sala.estado.Entidades[id] = EstadoEntidade{Tipo: TipoPortal, X: p.X, Y: p.Y}
+		if p.Saida != nil {
+			sala.saidasPortal[id] = *p.Saida
+		}
+	}
+	for i, p := range info.Armadilhas {
+		sala.estado.Entidades[idEntidade(idArmadilha, i)] = EstadoEntidade{Tipo: TipoArmadilha, X: p.X, Y: p.Y}
+	}
+}
+
+// idEntidade numera as entidades de um mesmo tipo: "guarda", "guarda-2", "guarda-3"...
+func idEntidade(base string, i int) string {
+	if i == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s-%d", base, i+1)
+}
+
+// avancarElementos move os elementos autônomos cujo movimento está agendado para o tick atual.
+// Entidades que ficaram de fora da rodada (iniciarElementos) não têm movimento agendado.
+func (sala *Sala) avancarElementos() {
+	for _, id := range idsOrdenados(sala.proximoMovimento) {
+		if sala.servidor.tick < sala.proximoMovimento[id] {
+			continue
+		}
+		var intervalo time.Duration
+		switch sala.estado.Entidades[id].Tipo {
+		case TipoGuarda:
+			intervalo = intervaloGuardaParado
+			if sala.moverGuarda(id) {
+				intervalo = intervaloGuardaMoveu
+			}
+		case TipoPortal:
+			sala.reposicionarEntidade(id)
+			intervalo = intervaloPortal
+		case TipoArmadilha:
+			sala.reposicionarEntidade(id)
+			intervalo = intervaloArmadilha
+		}
+		sala.proximoMovimento[id] = sala.servidor.tick + sala.servidor.ticksPara(intervalo)
+	}
+}
+
+// celulaLivre indica se (x, y) está dentro do mapa, não é tangível e não está
+// ocupada por jogador ou por entidade de tipo fora de permitidos.
+func (sala *Sala) celulaLivre(x, y int, permitidos ...string) bool {
+	if !jogoPodeMoverPara(&sala.mapa, x, y) {
+		return false
+	}
+	if _, ok := sala.ocupante(x, y); ok {
+		return false
+	}
+	for _, e := range sala.estado.Entidades {
+		if e.X == x && e.Y == y && !slices.Contains(permitidos, e.Tipo) {
+			return false
+		}
+	}
+	return true
+}
+
+// posicaoAleatoriaLivre sorteia uma célula vazia e livre do mapa. Se o sorteio não
+// encontrar nenhuma, percorre o mapa inteiro; retorna false se não houver célula livre.
+func (sala *Sala) posicaoAleatoriaLivre(origemX, origemY int) (int, int, bool) {
+	for tentativas := 0; tentativas < 100; tentativas++ {
+		x, y := jogoEncontrarSaida(sala.mapa.Mapa.Terreno, origemX, origemY, sala.servidor.rng)
+		if sala.celulaLivre(x, y) {
+			return x, y, true
+		}
+	}
+	for y, linha := range sala.mapa.Mapa.Terreno {
+		for x, elem := range linha {
+			if elem == Vazio && sala.celulaLivre(x, y) {
+				return x, y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// entidadeEm retorna o identificador e a entidade na posição (x, y), se houver.
+func (sala *Sala) entidadeEm(x, y int) (string, EstadoEntidade, bool) {
+	for _, id := range idsOrdenados(sala.estado.Entidades) {
+		if e := sala.estado.Entidades[id]; e.X == x && e.Y == y {
+			return id, e, true
+		}
+	}
+	return "", EstadoEntidade{}, false
+}
+
+// saidaPortal retorna o destino de quem entra no portal id: o portal ligado a ele,
+// se o mapa declarar o par e a célula estiver livre (fora o próprio portal), ou uma
+// célula livre sorteada. Retorna false se não houver célula livre.
+func (sala *Sala) saidaPortal(id string, x, y int) (int, int, bool) {
+	if saida, ok := sala.saidasPortal[id]; ok && sala.celulaLivre(saida.X, saida.Y, TipoPortal) {
+		return saida.X, saida.Y, true
+	}
+	return sala.posicaoAleatoriaLivre(x, y)
+}
+
+// moverGuarda faz o guarda id perseguir o jogador mais próximo ou andar aleatoriamente.
+// Retorna true se o guarda mudou de posição.
+func (sala *Sala) moverGuarda(id string) bool {
+	guarda := sala.estado.Entidades[id]
+	dx, dy := sala.servidor.rng.Intn(3)-1, sala.servidor.rng.Intn(3)-1
+
+	// Persegue o jogador vivo mais próximo dentro do alcance.
+	// Os jogadores são percorridos em ordem para que o resultado seja determinístico.
+	melhor := distanciaPerseguicao + 1
+	for _, id := range idsOrdenados(sala.estado.Jogadores) {
+		j := sala.estado.Jogadores[id]
+		if j.Vidas <= 0 {
+			continue
+		}
+		dist := abs(j.X-guarda.X) + abs(j.Y-guarda.Y)
+		if dist < melhor {
+			melhor = dist
+			dx, dy = sinal(j.X-guarda.X), sinal(j.Y-guarda.Y)
+		}
+	}
+
+	nx, ny := guarda.X+dx, guarda.Y+dy
+	if (dx == 0 && dy == 0) || !sala.celulaLivre(nx, ny) {
+		return false
+	}
+	guarda.X, guarda.Y = nx, ny
+	sala.estado.Entidades[id] = guarda
+	return true
+}
+
+// reposicionarEntidade move portal ou armadilha para uma posição aleatória livre.
+// Sem célula livre, a entidade fica onde está.
+func (sala *Sala) reposicionarEntidade(id string) {
+	entidade := sala.estado.Entidades[id]
+	if x, y, ok := sala.posicaoAleatoriaLivre(entidade.X, entidade.Y); ok {
+		entidade.X, entidade.Y = x, y
+		sala.estado.Entidades[id] = entidade
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sinal(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
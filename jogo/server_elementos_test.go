@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+// emparedarTeste transforma em parede todas as células livres do mapa da sala,
+// menos as informadas.
+func emparedarTeste(sala *Sala, livres ...Posicao) {
+	for y, linha := range sala.mapa.Mapa.Terreno {
+		for x, elem := range linha {
+			if elem.tangivel {
+				continue
+			}
+			manter := false
+			for _, p := range livres {
+				manter = manter || (p.X == x && p.Y == y)
+			}
+			if !manter {
+				linha[x] = Parede
+			}
+		}
+	}
+}
+
+func TestElementosSemCelulaLivre(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 2, Acao: "ready"})
+	sala := s.salas[SalaPrincipal]
+
+	// Resta livre apenas a célula ocupada por Ana
+	s.mu.Lock()
+	jogador := sala.estado.Jogadores[ana.ClientID]
+	emparedarTeste(sala, Posicao{X: jogador.X, Y: jogador.Y})
+	if x, y, ok := sala.posicaoAleatoriaLivre(jogador.X, jogador.Y); ok {
+		t.Errorf("posicaoAleatoriaLivre = (%d, %d) num mapa sem célula livre", x, y)
+	}
+	sala.saidasPortal["portal-teste"] = Posicao{X: jogador.X, Y: jogador.Y}
+	if x, y, ok := sala.saidaPortal("portal-teste", 0, 0); ok {
+		t.Errorf("saidaPortal = (%d, %d) sobre o jogador, esperada recusa", x, y)
+	}
+	s.mu.Unlock()
+
+	r := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+	if r.Codigo != CodigoRecusado || r.Motivo != MotivoSemCelulaLivre {
+		t.Errorf("registro = %v/%s, esperado %v/%s", r.Codigo, r.Motivo, CodigoRecusado, MotivoSemCelulaLivre)
+	}
+	if n := len(s.tokens); n != 1 {
+		t.Errorf("%d sessões após o registro recusado, esperada 1", n)
+	}
+
+	// Ana morre fora do mapa e não há onde reaparecer
+	s.mu.Lock()
+	jogador.X, jogador.Y, jogador.Vidas = -1, -1, 0
+	sala.definirJogador(ana.ClientID, jogador)
+	emparedarTeste(sala)
+	s.mu.Unlock()
+	r = s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3, Acao: "restart"})[0]
+	if r.Codigo != CodigoRecusado || r.Motivo != MotivoSemCelulaLivre {
+		t.Errorf("restart = %v/%s, esperado %v/%s", r.Codigo, r.Motivo, CodigoRecusado, MotivoSemCelulaLivre)
+	}
+	if j := sala.estado.Jogadores[ana.ClientID]; j.Vidas != 0 || j.X != -1 {
+		t.Errorf("jogador reposicionado sem célula livre: %+v", j)
+	}
+}
@@ -0,0 +1,189 @@
+// server_espectador.go - Espectadores: acompanhar uma sala sem entrar no jogo
+// Um espectador não se registra nem envia comandos: ele pede o mapa da sala (Assistir),
+// se inscreve no stream dela e recebe um snapshot completo a cada atualização
+// publicada. O cliente de terminal usa o modo -spectate (espectador.go); o gateway
+// (server_gateway.go) serve também uma página web que desenha o mapa com os
+// mesmos símbolos e cores do cliente de terminal (Elemento, jogo.go).
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// PrefixoEspectador inicia a linha de inscrição de um espectador: "ASSISTIR <sala>\n".
+const PrefixoEspectador = "ASSISTIR "
+
+// Página do espectador servida em GET / pelo gateway
+//
+//go:embed espectador.html
+var paginaEspectador []byte
+
+// estiloCelula descreve como desenhar um símbolo na página do espectador.
+type estiloCelula struct {
+	Simbolo string
+	Cor     string // Cor CSS do símbolo
+	Fundo   string // Cor CSS do fundo
+}
+
+// mapaEspectador é o mapa de uma sala como a página do espectador o recebe:
+// o mapa montado (spawns e elementos autônomos já removidos) e os estilos.
+type mapaEspectador struct {
+	Sala      string
+	Linhas    []string
+	Elementos []estiloCelula          // Estilo de cada símbolo que pode aparecer em Linhas
+	Entidades map[string]estiloCelula // Estilo de cada tipo de entidade autônoma
+	Jogador   estiloCelula            // Estilo dos jogadores
+}
+
+// servirEspectador inscreve um espectador na sala e envia um snapshot a cada atualização.
+func (s *JogoServer) servirEspectador(conn io.Closer, leitor *bufio.Reader, nomeSala string, enc codificadorEstado) {
+	defer conn.Close()
+	a := &assinante{nomeSala: nomeSala, aviso: make(chan struct{}, 1)}
+	s.servirAssinante(a, leitor, enc)
+}
+
+// Implementação do RPC: Assistir
+// Devolve o mapa e o estado completo da sala pedida em Comando.Sala (vazia: a principal)
+// e a lista de salas, sem registrar o espectador como jogador.
+func (s *JogoServer) Assistir(comando *Comando, resposta *Resposta) error {
+	if falha := recusaVersao(comando.Versao); falha != nil {
+		*resposta = *falha
+		return nil
+	}
+	nome := SalaPrincipal
+	if comando.Sala != nil && comando.Sala.Nome != "" {
+		nome = comando.Sala.Nome
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sala, ok := s.salas[nome]
+	if !ok {
+		*resposta = respostaFalha(CodigoNaoEncontrado, MotivoSalaNaoEncontrada, fmt.Sprintf("Sala %q não encontrada.", nome))
+		resposta.Salas = s.listarSalas()
+		return nil
+	}
+	*resposta = respostaOK(fmt.Sprintf("Assistindo à sala %s.", sala.nome))
+	resposta.Sala = sala.nome
+	resposta.Mapa = sala.linhasMapa
+	resposta.Delta = snapshotCompleto(sala.copiaEstado())
+	resposta.Salas = s.listarSalas()
+	return nil
+}
+
+// mapaParaEspectador monta o mapa da sala para a página do espectador.
+func (s *JogoServer) mapaParaEspectador(nomeSala string) (mapaEspectador, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sala, ok := s.salas[nomeSala]
+	if !ok {
+		return mapaEspectador{}, false
+	}
+	m := mapaEspectador{
+		Sala: sala.nome,
+		Elementos: []estiloCelula{
+			estiloElemento(Vazio), estiloElemento(Parede), estiloElemento(Vegetacao), estiloElemento(Inimigo),
+		},
+		Entidades: map[string]estiloCelula{
+			TipoGuarda:    estiloElemento(ElementoGuarda),
+			TipoPortal:    estiloElemento(ElementoPortal),
+			TipoArmadilha: estiloElemento(ElementoArmadilha),
+		},
+		Jogador: estiloElemento(OutroJogador),
+	}
+	for _, linha := range sala.mapa.Mapa.Terreno {
+		var b strings.Builder
+		for _, e := range linha {
+			b.WriteRune(e.simbolo)
+		}
+		m.Linhas = append(m.Linhas, b.String())
+	}
+	return m, true
+}
+
+// atenderPaginaEspectador responde aos pedidos HTTP comuns do gateway:
+// a página (/), a lista de salas (/salas) e o mapa de uma sala (/mapa?sala=<nome>).
+func (s *JogoServer) atenderPaginaEspectador(conn net.Conn, pedido *http.Request) {
+	switch pedido.URL.Path {
+	case "/":
+		responderHTTP(conn, http.StatusOK, "text/html; charset=utf-8", paginaEspectador)
+	case "/salas":
+		s.mu.Lock()
+		salas := s.listarSalas()
+		s.mu.Unlock()
+		responderJSON(conn, salas)
+	case "/mapa":
+		m, ok := s.mapaParaEspectador(pedido.URL.Query().Get("sala"))
+		if !ok {
+			responderHTTP(conn, http.StatusNotFound, "text/plain; charset=utf-8", []byte("sala não encontrada\n"))
+			return
+		}
+		responderJSON(conn, m)
+	default:
+		responderHTTP(conn, http.StatusNotFound, "text/plain; charset=utf-8", []byte("não encontrado\n"))
+	}
+}
+
+// responderJSON envia v em JSON como resposta HTTP.
+func responderJSON(conn net.Conn, v any) {
+	corpo, err := json.Marshal(v)
+	if err != nil {
+		responderHTTP(conn, http.StatusInternalServerError, "text/plain; charset=utf-8", []byte(err.Error()))
+		return
+	}
+	responderHTTP(conn, http.StatusOK, "application/json", corpo)
+}
+
+// responderHTTP escreve uma resposta HTTP/1.1 completa; a conexão é encerrada em seguida.
+func responderHTTP(conn net.Conn, status int, tipo string, corpo []byte) {
+	fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Type: %s\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+		status, http.StatusText(status), tipo, len(corpo))
+	conn.Write(corpo)
+}
+
+// estiloElemento converte um elemento do mapa para o estilo usado na página.
+func estiloElemento(e Elemento) estiloCelula {
+	return estiloCelula{
+		Simbolo: string(e.simbolo),
+		Cor:     corCSS(e.cor, "#c0c0c0"),
+		Fundo:   corCSS(e.corFundo, "transparent"),
+	}
+}
+
+// coresCSS traduz as cores do termbox para CSS.
+var coresCSS = map[Cor]string{
+	termbox.ColorBlack:        "#000000",
+	termbox.ColorRed:          "#cd3131",
+	termbox.ColorGreen:        "#0dbc79",
+	termbox.ColorYellow:       "#e5e510",
+	termbox.ColorBlue:         "#2472c8",
+	termbox.ColorMagenta:      "#bc3fbc",
+	termbox.ColorCyan:         "#11a8cd",
+	termbox.ColorWhite:        "#e5e5e5",
+	termbox.ColorDarkGray:     "#666666",
+	termbox.ColorLightRed:     "#f14c4c",
+	termbox.ColorLightGreen:   "#23d18b",
+	termbox.ColorLightYellow:  "#f5f543",
+	termbox.ColorLightBlue:    "#3b8eea",
+	termbox.ColorLightMagenta: "#d670d6",
+	termbox.ColorLightCyan:    "#29b8db",
+	termbox.ColorLightGray:    "#bbbbbb",
+}
+
+// corCSS retorna a cor CSS de uma cor do termbox, ignorando atributos como negrito.
+// A cor padrão do terminal vira padrao.
+func corCSS(c Cor, padrao string) string {
+	if css, ok := coresCSS[c&0x1FF]; ok {
+		return css
+	}
+	return padrao
+}
@@ -0,0 +1,121 @@
+// server_espera.go - Sala de espera (lobby) e confirmação de prontos antes da rodada
+// Uma sala começa na fase de espera: os jogadores se reúnem, veem a lista dos
+// demais e se marcam prontos ("ready"). A rodada começa quando todos estão prontos
+// ou quando a contagem, iniciada pelo primeiro jogador pronto, termina. Só então
+// os jogadores são posicionados e os elementos autônomos aparecem. Uma sala que
+// fica vazia durante a rodada volta para a espera.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContagemEsperaPadrao é o tempo máximo de espera após o primeiro jogador ficar pronto.
+const ContagemEsperaPadrao = 30 * time.Second
+
+// Resposta aos comandos de jogo enviados antes de a rodada começar
+const mensagemEspera = "A rodada ainda não começou. Pressione ENTER quando estiver pronto."
+
+// marcarPronto trata a ação "ready": alterna o estado de pronto do jogador na espera.
+// Deve ser chamada dentro de um tick.
+func (sala *Sala) marcarPronto(jogador *EstadoJogador) Resposta {
+	if sala.estado.Fase != FaseEspera {
+		return respostaFalha(CodigoRecusado, MotivoRodadaIniciada, "A rodada já começou.")
+	}
+	jogador.Pronto = !jogador.Pronto
+	if !jogador.Pronto {
+		return respostaOK("Você não está mais pronto.")
+	}
+	if sala.fimContagem == 0 {
+		sala.fimContagem = sala.servidor.tick + sala.servidor.ticksPara(sala.servidor.contagemEspera)
+	}
+	return respostaOK("Pronto! Aguardando os outros jogadores.")
+}
+
+// avancarEspera atualiza a fase da sala: inicia a rodada quando todos estão prontos
+// ou a contagem termina, cancela a contagem quando ninguém mais está pronto e volta
+// para a espera quando a sala fica vazia.
+// Deve ser chamada dentro de um tick.
+func (sala *Sala) avancarEspera() {
+	if len(sala.estado.Jogadores) == 0 {
+		if sala.estado.Fase != FaseEspera {
+			sala.encerrarRodada()
+		}
+		return
+	}
+	if sala.estado.Fase != FaseEspera {
+		return
+	}
+
+	todosProntos, algumPronto := true, false
+	for _, j := range sala.estado.Jogadores {
+		todosProntos = todosProntos && j.Pronto
+		algumPronto = algumPronto || j.Pronto
+	}
+
+	tick := sala.servidor.tick
+	switch {
+	case !algumPronto:
+		// Quem iniciou a contagem desistiu ou saiu: ela recomeça com o próximo pronto
+		sala.fimContagem = 0
+		sala.estado.Contagem = 0
+	case todosProntos:
+		sala.iniciarRodada("Todos prontos! A rodada começou.")
+	case sala.fimContagem != 0 && tick >= sala.fimContagem:
+		sala.iniciarRodada("Fim da contagem! A rodada começou.")
+	case sala.fimContagem != 0:
+		restante := time.Duration(sala.fimContagem-tick) * time.Second / time.Duration(sala.servidor.taxaTick)
+		sala.estado.Contagem = int((restante + time.Second - 1) / time.Second)
+	}
+}
+
+// iniciarRodada distribui os jogadores pelos pontos de spawn e posiciona os elementos autônomos.
+func (sala *Sala) iniciarRodada(mensagem string) {
+	// Tira todos do mapa antes de distribuí-los pelos pontos de spawn
+	ids := idsOrdenados(sala.estado.Jogadores)
+	for _, id := range ids {
+		j := sala.estado.Jogadores[id]
+		j.X, j.Y = -1, -1
+		sala.definirJogador(id, j)
+	}
+	if sala.geracao != nil && sala.aceitamMapaGerado() {
+		sala.regenerarMapa()
+	}
+	sala.estado.Entidades = make(map[string]EstadoEntidade)
+	var semCelula []string
+	for _, id := range ids {
+		j := sala.estado.Jogadores[id]
+		x, y, ok := sala.escolherSpawn()
+		j.Pronto = false
+		if !ok {
+			// Sem célula livre, o jogador fica fora do mapa até um restart
+			j.Vidas = 0
+			sala.definirJogador(id, j)
+			semCelula = append(semCelula, j.Nome)
+			continue
+		}
+		j.X, j.Y = x, y
+		j.Vidas = 3
+		sala.definirJogador(id, j)
+	}
+	sala.iniciarElementos()
+	sala.estado.Fase = FaseJogo
+	sala.estado.Contagem = 0
+	sala.fimContagem = 0
+	if len(semCelula) > 0 {
+		mensagem += fmt.Sprintf(" Sem célula livre para: %s.", strings.Join(semCelula, ", "))
+	}
+	sala.anunciar(mensagem)
+	sala.registrarEvento(EventoRodadaIniciada, "", -1, -1)
+	fmt.Printf("[Servidor] Rodada iniciada na sala %s com %d jogador(es)\n", sala.nome, len(sala.estado.Jogadores))
+}
+
+// encerrarRodada remove os elementos autônomos e devolve a sala à espera.
+func (sala *Sala) encerrarRodada() {
+	sala.estado.Entidades = make(map[string]EstadoEntidade)
+	sala.estado.Fase = FaseEspera
+	sala.estado.Contagem = 0
+	sala.fimContagem = 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEsperaContagem(t *testing.T) {
+	casos := []struct {
+		nome    string
+		desiste func(s *JogoServer, ana Resposta) // O que Ana faz depois de ficar pronta
+		fase    string
+	}{
+		{"pronto até o fim", func(*JogoServer, Resposta) {}, FaseJogo},
+		{"deixa de estar pronto", func(s *JogoServer, ana Resposta) {
+			s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3, Acao: "ready"})
+		}, FaseEspera},
+		{"sai do jogo", func(s *JogoServer, ana Resposta) {
+			s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3, Acao: "leave"})
+		}, FaseEspera},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			s := novoServidorTeste(t)
+			s.contagemEspera = time.Second
+			ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+			s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))
+
+			// Ana fica pronta e inicia a contagem; Bia nunca fica
+			s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 2, Acao: "ready"})
+			sala := s.salas[SalaPrincipal]
+			if sala.fimContagem == 0 {
+				t.Fatal("contagem não iniciada pelo primeiro pronto")
+			}
+			c.desiste(s, ana)
+
+			for i := uint64(0); i <= s.ticksPara(s.contagemEspera); i++ {
+				s.executarTeste()
+			}
+			if sala.estado.Fase != c.fase {
+				t.Errorf("fase após a contagem = %s, esperado %s", sala.estado.Fase, c.fase)
+			}
+			if c.fase == FaseEspera && (sala.fimContagem != 0 || sala.estado.Contagem != 0) {
+				t.Errorf("contagem mantida sem ninguém pronto: fim %d, %d s", sala.fimContagem, sala.estado.Contagem)
+			}
+		})
+	}
+}
+
+func TestEsperaMapaGerado(t *testing.T) {
+	semMapaGerado := slices.DeleteFunc(slices.Clone(RecursosSuportados), func(r string) bool { return r == RecursoMapaGerado })
+	casos := []struct {
+		nome       string
+		recursos   [3][]string // Recursos de Ana, Bia e Caio
+		novo       bool        // A primeira rodada começa com um mapa novo
+		caioSai    bool        // Caio sai antes da segunda rodada
+		novoDepois bool        // A segunda rodada começa com um mapa novo
+	}{
+		{"todos acordaram mapa_gerado", [3][]string{RecursosSuportados, RecursosSuportados, RecursosSuportados}, true, false, true},
+		{"um de três sem mapa_gerado", [3][]string{RecursosSuportados, RecursosSuportados, semMapaGerado}, false, false, false},
+		{"quem não tem mapa_gerado sai", [3][]string{RecursosSuportados, RecursosSuportados, semMapaGerado}, false, true, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			cfg := configPadrao()
+			cfg.Semente = 1
+			cfg.Gerador = "labirinto"
+			s, err := NovoJogoServer(cfg)
+			if err != nil {
+				t.Fatalf("NovoJogoServer: %v", err)
+			}
+			var jogadores []Resposta
+			for i, nome := range []string{"Ana", "Bia", "Caio"} {
+				registro := registroTeste(1, nome, "nonce-"+nome)
+				registro.Registro.Recursos = c.recursos[i]
+				jogadores = append(jogadores, s.executarTeste(registro)[0])
+			}
+			sala := s.salas[SalaPrincipal]
+			rodada := func(seq int, jogadores []Resposta) bool {
+				t.Helper()
+				antes := sala.linhasMapa
+				var prontos []Comando
+				for _, j := range jogadores {
+					prontos = append(prontos, Comando{ClientID: j.ClientID, Token: j.Token, SequenceNumber: seq, Acao: "ready"})
+				}
+				s.executarTeste(prontos...)
+				if sala.estado.Fase != FaseJogo {
+					t.Fatalf("fase = %s, esperado %s", sala.estado.Fase, FaseJogo)
+				}
+				return !slices.Equal(sala.linhasMapa, antes)
+			}
+
+			if novo := rodada(2, jogadores); novo != c.novo {
+				t.Errorf("primeira rodada com mapa novo = %v, esperado %v", novo, c.novo)
+			}
+
+			// A rodada só termina quando a sala esvazia; o teste a encerra direto
+			s.mu.Lock()
+			sala.encerrarRodada()
+			s.mu.Unlock()
+			if c.caioSai {
+				caio := jogadores[2]
+				s.executarTeste(Comando{ClientID: caio.ClientID, Token: caio.Token, SequenceNumber: 3, Acao: "leave"})
+				jogadores = jogadores[:2]
+			}
+			if novo := rodada(4, jogadores); novo != c.novoDepois {
+				t.Errorf("segunda rodada com mapa novo = %v, esperado %v", novo, c.novoDepois)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// server_gateway.go - Gateway JSON para clientes escritos em outras linguagens
+// Um segundo listener expõe as mesmas operações do RPC em Go, sobre o mesmo estado:
+//   - JSON-RPC 1.0 sobre TCP ({"method": "JogoServer.ExecutarComando", "params": [{...}], "id": 1});
+//   - a mesma linha de inscrição do stream ("INSCREVER <ClientID> <token>\n"), com um DeltaEstado
+//     em JSON por linha, e a de espectador ("ASSISTIR <sala>\n", server_espectador.go);
+//   - WebSocket (server_websocket.go): GET /rpc fala JSON-RPC, uma mensagem por
+//     chamada, GET /stream?id=<ClientID>&token=<token> envia um DeltaEstado em JSON por mensagem e
+//     GET /assistir?sala=<nome> faz o mesmo para um espectador (server_espectador.go);
+//   - HTTP comum: a página do espectador em GET /, com /salas e /mapa?sala=<nome>.
+//
+// As confirmações "ACK <tick>" do stream valem nas duas formas.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+)
+
+// PortaGatewayPadrao é a porta do gateway JSON quando nenhuma é informada.
+const PortaGatewayPadrao = "1235"
+
+// aceitarGateway atende as conexões do listener do gateway JSON.
+func (s *JogoServer) aceitarGateway(listener net.Listener, servidorRPC *rpc.Server) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println("Erro ao aceitar conexão no gateway:", err)
+			return
+		}
+		go s.atenderGateway(conn, servidorRPC)
+	}
+}
+
+// atenderGateway separa, pelos primeiros bytes, inscrições no stream, pedidos HTTP
+// (WebSocket) e chamadas JSON-RPC.
+func (s *JogoServer) atenderGateway(conn net.Conn, servidorRPC *rpc.Server) {
+	leitor := bufio.NewReader(conn)
+	inicio, _ := leitor.Peek(len(PrefixoStream))
+	switch {
+	case string(inicio) == PrefixoStream:
+		linha, err := leitor.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return
+		}
+		id, token := lerInscricao(linha)
+		s.servirStream(conn, leitor, id, token, json.NewEncoder(conn))
+	case strings.HasPrefix(string(inicio), PrefixoEspectador):
+		linha, err := leitor.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return
+		}
+		nomeSala := strings.TrimSpace(strings.TrimPrefix(linha, PrefixoEspectador))
+		s.servirEspectador(conn, leitor, nomeSala, json.NewEncoder(conn))
+	case strings.HasPrefix(string(inicio), "GET "):
+		s.atenderHTTP(conn, leitor, servidorRPC)
+	default:
+		servidorRPC.ServeCodec(jsonrpc.NewServerCodec(conexaoBufferizada{leitor, conn}))
+	}
+}
+
+// atenderHTTP trata um pedido HTTP no gateway: upgrade para WebSocket em /rpc, /stream
+// e /assistir; os demais pedidos vão para a página do espectador.
+func (s *JogoServer) atenderHTTP(conn net.Conn, leitor *bufio.Reader, servidorRPC *rpc.Server) {
+	pedido, err := http.ReadRequest(leitor)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if !pedidoWebSocket(pedido) {
+		s.atenderPaginaEspectador(conn, pedido)
+		conn.Close()
+		return
+	}
+	rota := pedido.URL.Path
+	if rota != "/rpc" && rota != "/stream" && rota != "/assistir" {
+		responderHTTP(conn, http.StatusNotFound, "text/plain; charset=utf-8", []byte("não encontrado\n"))
+		conn.Close()
+		return
+	}
+	ws, err := aceitarWebSocket(conn, leitor, pedido)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	switch rota {
+	case "/rpc":
+		servidorRPC.ServeCodec(jsonrpc.NewServerCodec(ws))
+	case "/stream":
+		consulta := pedido.URL.Query()
+		s.servirStream(ws, bufio.NewReader(ws), consulta.Get("id"), consulta.Get("token"), json.NewEncoder(ws))
+	case "/assistir":
+		s.servirEspectador(ws, bufio.NewReader(ws), pedido.URL.Query().Get("sala"), json.NewEncoder(ws))
+	}
+}
@@ -1,7 +1,34 @@
+//go:build servidor
+
 package main
 
 // Este arquivo é o ponto de entrada para rodar o Servidor RPC de forma isolada.
+// Compile com: go build -tags servidor -o server_jogo
+
+import (
+    "errors"
+    "flag"
+    "log"
+    "os"
+)
 
 func main() {
-    IniciarServidor("1234") 
-}
\ No newline at end of file
+    // Subcomando "validate": confere arquivos de mapa sem iniciar o servidor
+    if len(os.Args) > 1 && os.Args[1] == "validate" {
+        os.Exit(executarValidacao(os.Args[2:], os.Stdout))
+    }
+    // Subcomando "generate": grava um mapa gerado
+    if len(os.Args) > 1 && os.Args[1] == "generate" {
+        os.Exit(executarGeracao(os.Args[2:], os.Stdout))
+    }
+
+    // Porta, mapa, taxa de ticks, semente, spawn e timeout vêm das flags e do arquivo -config
+    cfg, err := configCarregar("server_jogo", os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        return
+    }
+    if err != nil {
+        log.Fatal("Erro na configuração:", err)
+    }
+    IniciarServidor(cfg)
+}
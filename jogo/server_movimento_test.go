@@ -0,0 +1,167 @@
+package main
+
+import "testing"
+
+// rodadaTeste registra os jogadores, inicia a rodada e tira da sala os elementos
+// autônomos. A área de (1, 1) a (5, 3) fica vazia, com o restante do mapa intacto.
+func rodadaTeste(t *testing.T, nomes ...string) (*JogoServer, *Sala, []Resposta) {
+	t.Helper()
+	s := novoServidorTeste(t)
+	registros := make([]Resposta, len(nomes))
+	prontos := make([]Comando, len(nomes))
+	for i, nome := range nomes {
+		registros[i] = s.executarTeste(registroTeste(1, nome, "nonce-"+nome))[0]
+		prontos[i] = Comando{ClientID: registros[i].ClientID, Token: registros[i].Token, SequenceNumber: 2, Acao: "ready"}
+	}
+	s.executarTeste(prontos...)
+
+	sala := s.salas[SalaPrincipal]
+	if sala.estado.Fase != FaseJogo {
+		t.Fatalf("fase = %s, esperado %s", sala.estado.Fase, FaseJogo)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sala.estado.Entidades = make(map[string]EstadoEntidade)
+	sala.proximoMovimento = make(map[string]uint64)
+	for y := 1; y <= 3; y++ {
+		for x := 1; x <= 5; x++ {
+			sala.mapa.Mapa.Terreno[y][x] = Vazio
+		}
+	}
+	return s, sala, registros
+}
+
+// posicionarTeste coloca o jogador em p. Deve ser chamada com s.mu travado.
+func posicionarTeste(sala *Sala, clientID string, p Posicao) {
+	jogador := sala.estado.Jogadores[clientID]
+	jogador.X, jogador.Y = p.X, p.Y
+	sala.definirJogador(clientID, jogador)
+}
+
+func TestMovimento(t *testing.T) {
+	alvo := Posicao{3, 2} // Célula à direita de Ana
+	casos := []struct {
+		nome       string
+		celula     Elemento // Terreno do alvo
+		de         Posicao  // Posição de Ana antes do movimento
+		direcao    string
+		bia        Posicao // Posição de Bia; o canto (0, 0), fora do caminho, se omitida
+		biaDirecao string  // Movimento de Bia no mesmo tick; vazio se ela ficar parada
+		motivo     string  // Motivo da recusa a Ana; vazio se o movimento for aceito
+		para       Posicao // Posição de Ana depois do movimento
+		vidas      int
+		simultaneo bool // Ana e Bia disputam o alvo: só uma entra, a outra é recusada
+	}{
+		{nome: "célula livre", celula: Vazio, de: Posicao{2, 2}, direcao: "d", para: alvo, vidas: 3},
+		{nome: "parede", celula: Parede, de: Posicao{2, 2}, direcao: "d", motivo: MotivoParede, para: Posicao{2, 2}, vidas: 3},
+		{nome: "inimigo", celula: Inimigo, de: Posicao{2, 2}, direcao: "d", motivo: MotivoInimigo, para: Posicao{2, 2}, vidas: 2},
+		{nome: "fora do mapa", celula: Vazio, de: Posicao{0, 2}, direcao: "a", motivo: MotivoForaDoMapa, para: Posicao{0, 2}, vidas: 3},
+		{nome: "célula ocupada", celula: Vazio, de: Posicao{2, 2}, direcao: "d", bia: alvo,
+			motivo: MotivoCelulaOcupada, para: Posicao{2, 2}, vidas: 3},
+		{nome: "célula deixada no mesmo tick", celula: Vazio, de: Posicao{2, 2}, direcao: "d", bia: alvo, biaDirecao: "d",
+			motivo: MotivoCelulaOcupada, para: Posicao{2, 2}, vidas: 3},
+		{nome: "movimento simultâneo", celula: Vazio, de: Posicao{2, 2}, direcao: "d", bia: Posicao{4, 2}, biaDirecao: "a",
+			simultaneo: true},
+	}
+	for _, c := range casos {
+		// Com Bia também se movendo, o resultado não pode depender de quem vem antes
+		// no tick: cada caso roda de novo com a ordem de Ana e Bia trocada
+		for _, trocada := range []bool{false, true} {
+			if trocada && c.biaDirecao == "" {
+				continue
+			}
+			nome := c.nome
+			if trocada {
+				nome += " (ordem trocada)"
+			}
+			t.Run(nome, func(t *testing.T) {
+				s, sala, registros := rodadaTeste(t, "Ana", "Bia")
+				ana, bia := registros[0], registros[1]
+				s.mu.Lock()
+				if trocada {
+					// Mesma semente, mesmo embaralhamento (ordemNoTick): trocar a ordem de
+					// registro troca quem vem antes no tick
+					s.ordemRegistro[ana.ClientID], s.ordemRegistro[bia.ClientID] = s.ordemRegistro[bia.ClientID], s.ordemRegistro[ana.ClientID]
+				}
+				sala.mapa.Mapa.Terreno[alvo.Y][alvo.X] = c.celula
+				posicionarTeste(sala, bia.ClientID, c.bia)
+				posicionarTeste(sala, ana.ClientID, c.de)
+				s.mu.Unlock()
+
+				comandos := []Comando{{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3,
+					Acao: "move", Movimento: &DadosMovimento{Direcao: c.direcao}}}
+				if c.biaDirecao != "" {
+					comandos = append(comandos, Comando{ClientID: bia.ClientID, Token: bia.Token, SequenceNumber: 3,
+						Acao: "move", Movimento: &DadosMovimento{Direcao: c.biaDirecao}})
+				}
+				respostas := s.executarTeste(comandos...)
+				if c.simultaneo {
+					verificarSimultaneo(t, sala, alvo, [2]Resposta{ana, bia}, [2]Posicao{c.de, c.bia}, [2]Resposta{respostas[0], respostas[1]})
+					return
+				}
+
+				r := respostas[0]
+				if r.Motivo != c.motivo || r.Ok() != (c.motivo == "") {
+					t.Errorf("resposta = %v/%s %q, esperado o motivo %q", r.Codigo, r.Motivo, r.Mensagem, c.motivo)
+				}
+				j := sala.estado.Jogadores[ana.ClientID]
+				if (Posicao{j.X, j.Y}) != c.para || j.Vidas != c.vidas {
+					t.Errorf("Ana em (%d, %d) com %d vidas, esperado %v com %d", j.X, j.Y, j.Vidas, c.para, c.vidas)
+				}
+			})
+		}
+	}
+}
+
+// verificarSimultaneo confere que, de dois jogadores que pediram o alvo no mesmo tick,
+// exatamente um entrou nele e o outro foi recusado e ficou onde estava.
+func verificarSimultaneo(t *testing.T, sala *Sala, alvo Posicao, registros [2]Resposta, origens [2]Posicao, respostas [2]Resposta) {
+	t.Helper()
+	aceitos := 0
+	for i, r := range respostas {
+		j := sala.estado.Jogadores[registros[i].ClientID]
+		switch {
+		case r.Ok():
+			aceitos++
+			if (Posicao{j.X, j.Y}) != alvo {
+				t.Errorf("movimento aceito, mas o jogador está em (%d, %d)", j.X, j.Y)
+			}
+		case r.Motivo != MotivoMovimentoSimultaneo:
+			t.Errorf("recusa = %v/%s, esperado %s", r.Codigo, r.Motivo, MotivoMovimentoSimultaneo)
+		case (Posicao{j.X, j.Y}) != origens[i]:
+			t.Errorf("jogador recusado foi para (%d, %d), esperado %v", j.X, j.Y, origens[i])
+		}
+	}
+	if aceitos != 1 {
+		t.Errorf("%d movimentos aceitos para a mesma célula, esperado 1", aceitos)
+	}
+}
+
+func TestMovimentoSimultaneoDeterministico(t *testing.T) {
+	// Com a mesma semente, os mesmos registros e os mesmos comandos, vence sempre o
+	// mesmo jogador, embora os ClientIDs sejam sorteados a cada servidor
+	alvo := Posicao{3, 2}
+	vencedor := ""
+	for i := 0; i < 8; i++ {
+		s, sala, registros := rodadaTeste(t, "Ana", "Bia")
+		ana, bia := registros[0], registros[1]
+		s.mu.Lock()
+		posicionarTeste(sala, ana.ClientID, Posicao{2, 2})
+		posicionarTeste(sala, bia.ClientID, Posicao{4, 2})
+		s.mu.Unlock()
+
+		s.executarTeste(
+			Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3, Acao: "move", Movimento: &DadosMovimento{Direcao: "d"}},
+			Comando{ClientID: bia.ClientID, Token: bia.Token, SequenceNumber: 3, Acao: "move", Movimento: &DadosMovimento{Direcao: "a"}})
+		c, ok := sala.ocupante(alvo.X, alvo.Y)
+		if !ok {
+			t.Fatalf("execução %d: ninguém entrou em %v", i, alvo)
+		}
+		ocupante := sala.estado.Jogadores[c.clientID].Nome
+		if vencedor == "" {
+			vencedor = ocupante
+		} else if ocupante != vencedor {
+			t.Fatalf("execução %d: %s entrou no alvo, antes foi %s", i, ocupante, vencedor)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// server_respostas.go - Cache de respostas por cliente para execução única (exactly-once)
+// Um Comando reenviado com um SequenceNumber já processado recebe exatamente a
+// Resposta produzida da primeira vez, inclusive quando ela foi um erro. As respostas
+// do "register", que ainda não tem ClientID, são guardadas pelo nonce do registro e
+// presas ao nome pedido: um registro com o mesmo nonce e outro nome é recusado, em
+// vez de receber o ClientID e o token de outro jogador.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limites do cache: respostas guardadas por cliente e tempo até descartar um cliente inativo
+const (
+	maxRespostasPorCliente = 32
+	tempoCacheInativo      = 2 * time.Minute
+)
+
+// Prefixo das chaves do cache para as respostas de "register", seguido do nonce
+const prefixoCacheRegistro = "registro/"
+
+// cacheRespostas guarda as últimas respostas produzidas para um cliente.
+type cacheRespostas struct {
+	ultimoSeq  int              // Maior número de sequência já processado
+	respostas  map[int]Resposta // Respostas por número de sequência
+	ordem      []int            // Números de sequência na ordem em que foram guardados
+	ultimoTick uint64           // Tick do último comando recebido do cliente
+	nome       string           // Nome pedido no registro (apenas nas chaves de registro)
+}
+
+// chaveCache identifica o remetente do comando no cache de respostas: o nonce, num
+// "register" que o traz, ou o ClientID. Sem nenhum dos dois, retorna "".
+func chaveCache(comando *Comando) string {
+	if comando.Acao == "register" && comando.Registro != nil && comando.Registro.Nonce != "" {
+		return prefixoCacheRegistro + comando.Registro.Nonce
+	}
+	return comando.ClientID
+}
+
+// nomeRegistro retorna o nome pedido num "register", ou "" nas demais ações.
+func nomeRegistro(comando *Comando) string {
+	if comando.Registro == nil {
+		return ""
+	}
+	return comando.Registro.Nome
+}
+
+// respostaEmCache retorna a resposta original de um comando já processado.
+// Se o comando for antigo demais para ainda estar no cache, retorna um erro em vez
+// de executá-lo de novo. Deve ser chamada com s.mu travado.
+func (s *JogoServer) respostaEmCache(comando *Comando) (Resposta, bool) {
+	chave := chaveCache(comando)
+	cache, ok := s.respostas[chave]
+	if !ok {
+		return Resposta{}, false
+	}
+	if strings.HasPrefix(chave, prefixoCacheRegistro) && cache.nome != nomeRegistro(comando) {
+		return respostaFalha(CodigoConflito, MotivoNonceEmUso, "Nonce do registro já usado por outro registro."), true
+	}
+	if comando.SequenceNumber > cache.ultimoSeq {
+		return Resposta{}, false
+	}
+	cache.ultimoTick = s.tick
+	if resposta, ok := cache.respostas[comando.SequenceNumber]; ok {
+		return resposta, true
+	}
+	return respostaFalha(CodigoExpirado, MotivoForaDoCache,
+		fmt.Sprintf("Comando %d já processado e fora do cache de respostas.", comando.SequenceNumber)), true
+}
+
+// respostaOK cria a resposta de um comando aplicado, com uma mensagem opcional.
+func respostaOK(mensagem string) Resposta {
+	return Resposta{Codigo: CodigoOK, Mensagem: mensagem}
+}
+
+// respostaFalha cria a resposta de um comando que não foi aplicado.
+func respostaFalha(codigo CodigoResposta, motivo, mensagem string) Resposta {
+	return Resposta{Codigo: codigo, Motivo: motivo, Mensagem: mensagem}
+}
+
+// guardarResposta registra a resposta de um comando recém-processado.
+// São guardados os registros com nonce, mesmo quando recusados, os comandos de
+// jogadores registrados e o "leave" aceito, que já tirou o jogador do estado. Um
+// registro com o nonce de outro nome, executado no mesmo tick que o original, não
+// substitui a resposta dele. Deve ser chamada com s.mu travado.
+func (s *JogoServer) guardarResposta(comando *Comando, resposta Resposta) {
+	chave := chaveCache(comando)
+	if _, ok := s.salaDe[chave]; !ok && !strings.HasPrefix(chave, prefixoCacheRegistro) && !(comando.Acao == "leave" && resposta.Ok()) {
+		return
+	}
+	cache, ok := s.respostas[chave]
+	if !ok {
+		cache = &cacheRespostas{respostas: make(map[int]Resposta), nome: nomeRegistro(comando)}
+		s.respostas[chave] = cache
+	} else if cache.nome != nomeRegistro(comando) {
+		return
+	}
+
+	cache.respostas[comando.SequenceNumber] = resposta
+	cache.ordem = append(cache.ordem, comando.SequenceNumber)
+	if comando.SequenceNumber > cache.ultimoSeq {
+		cache.ultimoSeq = comando.SequenceNumber
+	}
+	cache.ultimoTick = s.tick
+
+	for len(cache.ordem) > maxRespostasPorCliente {
+		delete(cache.respostas, cache.ordem[0])
+		cache.ordem = cache.ordem[1:]
+	}
+}
+
+// descartarRespostas remove o cache de um cliente que se desconectou. As respostas
+// guardadas pelo nonce do registro só expiram por inatividade (expirarRespostas).
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) descartarRespostas(clientID string) {
+	delete(s.respostas, clientID)
+}
+
+// expirarRespostas descarta o cache dos clientes que não enviam comandos há muito tempo.
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) expirarRespostas() {
+	limite := s.ticksPara(tempoCacheInativo)
+	for id, cache := range s.respostas {
+		if s.tick-cache.ultimoTick > limite {
+			s.descartarRespostas(id)
+		}
+	}
+}
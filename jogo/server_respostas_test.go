@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// novoServidorTeste cria um servidor com o mapa padrão e semente fixa, sem iniciar
+// o loop de ticks: os testes avançam a simulação com executarTeste.
+func novoServidorTeste(t *testing.T) *JogoServer {
+	t.Helper()
+	cfg := configPadrao()
+	cfg.Semente = 1
+	s, err := NovoJogoServer(cfg)
+	if err != nil {
+		t.Fatalf("NovoJogoServer: %v", err)
+	}
+	return s
+}
+
+// executarTeste enfileira os comandos, executa um tick e retorna as respostas, na
+// ordem dos comandos, como ExecutarComando as devolveria.
+func (s *JogoServer) executarTeste(comandos ...Comando) []Resposta {
+	canais := make([]chan Resposta, len(comandos))
+	pendentes := make([]comandoPendente, len(comandos))
+	for i, comando := range comandos {
+		comando.Versao = VersaoProtocolo
+		canais[i] = make(chan Resposta, 1)
+		pendentes[i] = s.novoPendente(comando, canais[i])
+	}
+	s.mu.Lock()
+	s.fila = append(s.fila, pendentes...)
+	s.mu.Unlock()
+	s.executarTick()
+	respostas := make([]Resposta, len(comandos))
+	for i, canal := range canais {
+		respostas[i] = <-canal
+	}
+	return respostas
+}
+
+// registroTeste monta um "register" com o nome informado e o nonce de rotulo (nonceTeste).
+func registroTeste(seq int, nome, rotulo string) Comando {
+	return Comando{
+		SequenceNumber: seq,
+		Acao:           "register",
+		Registro:       &DadosRegistro{Nome: nome, Recursos: RecursosSuportados, Nonce: nonceTeste(rotulo)},
+	}
+}
+
+// nonceTeste completa o rótulo até o tamanho mínimo de um nonce de registro.
+func nonceTeste(rotulo string) string {
+	if len(rotulo) >= minTamanhoNonce {
+		return rotulo
+	}
+	return rotulo + strings.Repeat("0", minTamanhoNonce-len(rotulo))
+}
+
+func TestRespostaRegistroReenviado(t *testing.T) {
+	casos := []struct {
+		nome        string
+		nomeJogador string
+		mesmoTick   bool // A cópia chega no mesmo tick que o original
+	}{
+		{"sem nome", "", false},
+		{"com nome", "Ana", false},
+		{"sem nome no mesmo tick", "", true},
+		{"com nome no mesmo tick", "Ana", true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			s := novoServidorTeste(t)
+			registro := registroTeste(1, c.nomeJogador, "nonce-1")
+
+			var original, repetida Resposta
+			if c.mesmoTick {
+				respostas := s.executarTeste(registro, registro)
+				original, repetida = respostas[0], respostas[1]
+			} else {
+				original = s.executarTeste(registro)[0]
+				s.executarTeste()
+				repetida = s.executarTeste(registro)[0]
+			}
+
+			if !original.Ok() {
+				t.Fatalf("registro recusado: %v %s", original.Codigo, original.Mensagem)
+			}
+			if repetida.Codigo != original.Codigo || repetida.ClientID != original.ClientID || repetida.Token != original.Token {
+				t.Errorf("reenvio = %v %q, esperada a resposta original %v %q",
+					repetida.Codigo, repetida.ClientID, original.Codigo, original.ClientID)
+			}
+			if n := len(s.salas[SalaPrincipal].estado.Jogadores); n != 1 {
+				t.Errorf("%d jogadores na sala após o reenvio, esperado 1", n)
+			}
+		})
+	}
+}
+
+func TestRespostaRegistroOutroNonce(t *testing.T) {
+	s := novoServidorTeste(t)
+	if r := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]; !r.Ok() {
+		t.Fatalf("registro recusado: %s", r.Mensagem)
+	}
+	// Outro cliente (outro nonce) com o mesmo número de sequência e o mesmo nome
+	r := s.executarTeste(registroTeste(1, "Ana", "nonce-2"))[0]
+	if r.Codigo != CodigoConflito || r.Motivo != MotivoNomeEmUso {
+		t.Errorf("resposta = %v/%s, esperado %v/%s", r.Codigo, r.Motivo, CodigoConflito, MotivoNomeEmUso)
+	}
+	// A recusa também é a resposta guardada para os reenvios desse registro
+	if r := s.executarTeste(registroTeste(1, "Ana", "nonce-2"))[0]; r.Motivo != MotivoNomeEmUso {
+		t.Errorf("reenvio da recusa = %v/%s, esperado %s", r.Codigo, r.Motivo, MotivoNomeEmUso)
+	}
+}
+
+func TestRespostaRegistroNonceDeOutroNome(t *testing.T) {
+	casos := []struct {
+		nome      string
+		mesmoTick bool // O registro com outro nome chega no mesmo tick que o original
+		seq       int
+	}{
+		{"reenvio", false, 1},
+		{"número de sequência maior", false, 2},
+		{"no mesmo tick", true, 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			s := novoServidorTeste(t)
+			original := registroTeste(1, "Ana", "nonce-1")
+			intruso := registroTeste(c.seq, "Eva", "nonce-1")
+
+			var ana, eva Resposta
+			if c.mesmoTick {
+				respostas := s.executarTeste(original, intruso)
+				ana, eva = respostas[0], respostas[1]
+			} else {
+				ana = s.executarTeste(original)[0]
+				eva = s.executarTeste(intruso)[0]
+			}
+			if !ana.Ok() {
+				t.Fatalf("registro recusado: %s", ana.Mensagem)
+			}
+			if eva.Token == ana.Token || eva.ClientID == ana.ClientID {
+				t.Fatalf("outro nome recebeu a sessão de Ana: %+v", eva)
+			}
+			if !c.mesmoTick && (eva.Codigo != CodigoConflito || eva.Motivo != MotivoNonceEmUso) {
+				t.Errorf("resposta = %v/%s, esperado %v/%s", eva.Codigo, eva.Motivo, CodigoConflito, MotivoNonceEmUso)
+			}
+
+			// O reenvio de Ana continua recebendo a resposta original
+			if r := s.executarTeste(original)[0]; r.Token != ana.Token {
+				t.Errorf("reenvio de Ana = %v %q, esperado o token original", r.Codigo, r.ClientID)
+			}
+		})
+	}
+}
+
+func TestRespostaRegistroNonceCurto(t *testing.T) {
+	casos := []struct {
+		nonce  string
+		codigo CodigoResposta
+	}{
+		{"", CodigoOK}, // Sem nonce o registro é aceito, mas não é guardado no cache
+		{"1", CodigoInvalido},
+		{strings.Repeat("a", minTamanhoNonce-1), CodigoInvalido},
+		{strings.Repeat("a", minTamanhoNonce), CodigoOK},
+		{strings.Repeat("a", maxTamanhoNonce+1), CodigoInvalido},
+	}
+	for _, c := range casos {
+		t.Run(fmt.Sprintf("%d bytes", len(c.nonce)), func(t *testing.T) {
+			s := novoServidorTeste(t)
+			registro := registroTeste(1, "Ana", "")
+			registro.Registro.Nonce = c.nonce
+			if r := s.executarTeste(registro)[0]; r.Codigo != c.codigo {
+				t.Errorf("resposta = %v/%s, esperado %v", r.Codigo, r.Motivo, c.codigo)
+			}
+		})
+	}
+}
+
+func TestRespostaVersaoDiferente(t *testing.T) {
+	// Cada versão rompe com as demais: clientes mais antigos e mais novos são recusados
+	for _, versao := range []int{0, VersaoProtocolo - 1, VersaoProtocolo, VersaoProtocolo + 1} {
+		r := recusaVersao(versao)
+		if recusado := r != nil; recusado != (versao != VersaoProtocolo) {
+			t.Errorf("v%d: recusado = %v", versao, recusado)
+			continue
+		}
+		if r != nil && (r.Codigo != CodigoIncompativel || r.Motivo != MotivoProtocoloIncompativel) {
+			t.Errorf("v%d: recusa %v/%s, esperado %v/%s", versao, r.Codigo, r.Motivo, CodigoIncompativel, MotivoProtocoloIncompativel)
+		}
+	}
+}
+
+func TestRespostaComandoInvalido(t *testing.T) {
+	movimento := &DadosMovimento{Direcao: "d"}
+	casos := []struct {
+		nome    string
+		comando Comando
+		motivo  string
+	}{
+		{"ação desconhecida", Comando{Acao: "voar"}, MotivoAcaoDesconhecida},
+		{"ação vazia", Comando{}, MotivoAcaoDesconhecida},
+		{"move sem movimento", Comando{Acao: "move"}, MotivoFormatoInvalido},
+		{"chat sem mensagem", Comando{Acao: "chat"}, MotivoFormatoInvalido},
+		{"join sem sala", Comando{Acao: "join"}, MotivoFormatoInvalido},
+		{"movimento num chat", Comando{Acao: "chat", Chat: &DadosChat{Texto: "oi"}, Movimento: movimento}, MotivoFormatoInvalido},
+		{"movimento num ready", Comando{Acao: "ready", Movimento: movimento}, MotivoFormatoInvalido},
+		{"chat num move", Comando{Acao: "move", Movimento: movimento, Chat: &DadosChat{Texto: "oi"}}, MotivoFormatoInvalido},
+		{"create sem mapa", Comando{Acao: "create", Sala: &DadosSala{Nome: "arena"}}, MotivoFormatoInvalido},
+		{"direção inválida", Comando{Acao: "move", Movimento: &DadosMovimento{Direcao: "x"}}, MotivoDirecaoInvalida},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			s := novoServidorTeste(t)
+			registro := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]
+			antes := s.salas[SalaPrincipal].estado.Jogadores[registro.ClientID]
+
+			c.comando.ClientID, c.comando.Token, c.comando.SequenceNumber = registro.ClientID, registro.Token, 2
+			r := s.executarTeste(c.comando)[0]
+			if r.Codigo != CodigoInvalido || r.Motivo != c.motivo {
+				t.Errorf("resposta = %v/%s %q, esperado %v/%s", r.Codigo, r.Motivo, r.Mensagem, CodigoInvalido, c.motivo)
+			}
+			// O comando recusado não toca no estado nem leva o jogador a outra sala
+			if sala := s.salaDe[registro.ClientID]; sala != s.salas[SalaPrincipal] || len(s.salas) != 1 {
+				t.Errorf("jogador na sala %q, salas %v", sala.nome, idsOrdenados(s.salas))
+			}
+			if depois := s.salas[SalaPrincipal].estado.Jogadores[registro.ClientID]; depois.X != antes.X || depois.Y != antes.Y {
+				t.Errorf("jogador foi de (%d, %d) para (%d, %d)", antes.X, antes.Y, depois.X, depois.Y)
+			}
+		})
+	}
+}
+
+func TestRespostaComandoReenviado(t *testing.T) {
+	s := novoServidorTeste(t)
+	registro := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]
+	pronto := Comando{ClientID: registro.ClientID, Token: registro.Token, SequenceNumber: 2, Acao: "ready"}
+
+	// Sozinho na sala, o "ready" inicia a rodada; o reenvio não pode recusá-lo como
+	// "rodada já iniciada" nem alternar o estado de pronto de novo
+	original := s.executarTeste(pronto)[0]
+	repetida := s.executarTeste(pronto)[0]
+	if !original.Ok() {
+		t.Fatalf("ready recusado: %s", original.Mensagem)
+	}
+	if repetida.Codigo != original.Codigo || repetida.Mensagem != original.Mensagem {
+		t.Errorf("reenvio = %v %q, esperado %v %q", repetida.Codigo, repetida.Mensagem, original.Codigo, original.Mensagem)
+	}
+	if fase := s.salas[SalaPrincipal].estado.Fase; fase != FaseJogo {
+		t.Errorf("fase = %s, esperado %s", fase, FaseJogo)
+	}
+}
+
+func TestRespostaLeaveReenviado(t *testing.T) {
+	s := novoServidorTeste(t)
+	registro := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]
+	saida := Comando{ClientID: registro.ClientID, Token: registro.Token, SequenceNumber: 2, Acao: "leave"}
+
+	// O "leave" tira o jogador do estado; o reenvio, sem sessão, ainda recebe a
+	// resposta original em vez de "não registrado"
+	original := s.executarTeste(saida)[0]
+	repetida := s.executarTeste(saida)[0]
+	if !original.Ok() {
+		t.Fatalf("leave recusado: %s", original.Mensagem)
+	}
+	if repetida.Codigo != original.Codigo || repetida.Mensagem != original.Mensagem {
+		t.Errorf("reenvio = %v/%s %q, esperado %v %q", repetida.Codigo, repetida.Motivo, repetida.Mensagem, original.Codigo, original.Mensagem)
+	}
+	if _, ok := s.salaDe[registro.ClientID]; ok {
+		t.Error("jogador continua registrado depois do leave")
+	}
+}
+
+func TestRespostaForaDoCache(t *testing.T) {
+	s := novoServidorTeste(t)
+	registro := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]
+	mover := func(seq int) Comando {
+		return Comando{ClientID: registro.ClientID, Token: registro.Token, SequenceNumber: seq,
+			Acao: "move", Movimento: &DadosMovimento{Direcao: "d"}}
+	}
+	ultimo := maxRespostasPorCliente + 5
+	for seq := 2; seq <= ultimo; seq++ {
+		s.executarTeste(mover(seq))
+	}
+
+	casos := []struct {
+		seq    int
+		codigo CodigoResposta
+	}{
+		{2, CodigoExpirado}, // Descartada do cache
+		{ultimo - maxRespostasPorCliente, CodigoExpirado},
+		{ultimo - maxRespostasPorCliente + 1, CodigoRecusado}, // Ainda no cache: rodada não iniciada
+		{ultimo, CodigoRecusado},
+	}
+	for _, c := range casos {
+		t.Run(fmt.Sprintf("seq %d", c.seq), func(t *testing.T) {
+			if r := s.executarTeste(mover(c.seq))[0]; r.Codigo != c.codigo {
+				t.Errorf("resposta = %v/%s, esperado %v", r.Codigo, r.Motivo, c.codigo)
+			}
+		})
+	}
+}
+
+func TestRespostaExpiraPorInatividade(t *testing.T) {
+	s := novoServidorTeste(t)
+	registro := s.executarTeste(registroTeste(1, "Ana", "nonce-1"))[0]
+	chaves := []string{registro.ClientID, prefixoCacheRegistro + nonceTeste("nonce-1")}
+	s.executarTeste(Comando{ClientID: registro.ClientID, Token: registro.Token, SequenceNumber: 2, Acao: "ready"})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chave := range chaves {
+		if _, ok := s.respostas[chave]; !ok {
+			t.Fatalf("cache %q ausente após os comandos", chave)
+		}
+	}
+	s.tick += s.ticksPara(tempoCacheInativo) + 1
+	s.expirarRespostas()
+	for _, chave := range chaves {
+		if _, ok := s.respostas[chave]; ok {
+			t.Errorf("cache %q mantido após %v sem comandos", chave, tempoCacheInativo)
+		}
+	}
+}
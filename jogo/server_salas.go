@@ -0,0 +1,421 @@
+// server_salas.go - Salas: instâncias isoladas do jogo hospedadas pelo mesmo servidor
+// Cada sala tem seu próprio mapa, jogadores, elementos autônomos, avisos e histórico
+// de estados. Todas avançam no mesmo tick (server_tick.go). A sala principal usa o
+// mapa da configuração (ou, com -gerar, um mapa gerado a cada rodada) e sempre existe;
+// as demais são criadas pelos jogadores e removidas quando ficam vazias.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"T1fppd/gerador"
+)
+
+// SalaPrincipal é a sala em que os jogadores entram ao se registrar.
+const SalaPrincipal = "principal"
+
+// Tamanho máximo, em caracteres, do nome de uma sala
+const maxTamanhoNomeSala = 16
+
+// Sala é uma instância do jogo com estado próprio. Os campos e os métodos da sala são
+// protegidos por sala.servidor.mu: só devem ser usados com ele travado.
+type Sala struct {
+	nome         string
+	arquivoMapa  string
+	linhasMapa   []string        // Texto do mapa, enviado aos clientes que entram na sala
+	mapa         Jogo            // Mapa usado para validar movimentos
+	spawns       []Posicao       // Pontos de spawn do mapa (nunca vazio)
+	proximoSpawn int             // Próximo ponto de spawn do rodízio
+	permanente   bool            // A sala principal nunca é removida
+	geracao      *gerador.Opcoes // Opções do gerador, se a sala ganha um mapa novo a cada rodada
+	servidor     *JogoServer
+
+	estado           EstadoJogo
+	ocupacao         [][]celulaOcupada  // Jogador em cada célula do mapa (server_colisao.go)
+	proximoMovimento map[string]uint64  // Tick do próximo movimento de cada entidade autônoma
+	saidasPortal     map[string]Posicao // Saída de cada portal ligado a outro no mapa
+	fimContagem      uint64             // Tick em que a espera termina (0: contagem não iniciada)
+	eventos          []Evento           // Acontecimentos do tick atual
+
+	// Histórico para deltas (server_delta.go) e último estado enviado ao stream (server_stream.go)
+	ultimoPublicado EstadoJogo
+	historico       []EstadoJogo
+}
+
+// mapaCarregado é o mapa de uma sala, já lido e validado. Ler e validar não depende do
+// estado do servidor: o mapa de um "create" é carregado fora do lock (carregarMapaPedido).
+type mapaCarregado struct {
+	origem string // Arquivo do mapa, ou "gerado:" e o valor de -gerar
+	linhas []string
+	mapa   Jogo
+}
+
+// carregarMapa lê e valida o mapa do arquivo. Pode ser chamada sem s.mu travado.
+func carregarMapa(arquivo string) (mapaCarregado, error) {
+	linhas, err := jogoLerArquivoMapa(arquivo)
+	if err != nil {
+		return mapaCarregado{}, err
+	}
+	return interpretarMapa(arquivo, linhas)
+}
+
+// interpretarMapa valida as linhas de um mapa vindo de origem. Pode ser chamada sem s.mu travado.
+func interpretarMapa(origem string, linhas []string) (mapaCarregado, error) {
+	mapa := jogoNovo()
+	if err := jogoMontarMapa(linhas, &mapa); err != nil {
+		return mapaCarregado{}, fmt.Errorf("%s: %w", origem, err)
+	}
+	return mapaCarregado{origem: origem, linhas: linhas, mapa: mapa}, nil
+}
+
+// novaSala carrega o mapa e escolhe a posição inicial. A sala começa na espera
+// (server_espera.go); os elementos autônomos só aparecem quando a rodada começa.
+// Deve ser chamada antes de o servidor começar a atender.
+func (s *JogoServer) novaSala(nome, arquivoMapa string) (*Sala, error) {
+	carregado, err := carregarMapa(arquivoMapa)
+	if err != nil {
+		return nil, err
+	}
+	return s.montarSala(nome, carregado)
+}
+
+// novaSalaGerada cria uma sala com um mapa do gerador, descrito como no valor de -gerar
+// (mapa_geracao.go). A cada rodada a sala ganha um mapa novo (regenerarMapa).
+// Deve ser chamada com s.mu travado (ou antes de o servidor começar a atender).
+func (s *JogoServer) novaSalaGerada(nome, valor string) (*Sala, error) {
+	opcoes, err := opcoesGeracao(valor)
+	if err != nil {
+		return nil, err
+	}
+	opcoes.Semente = s.rng.Int63()
+	linhas, err := gerarMapaConferido(opcoes)
+	if err != nil {
+		return nil, err
+	}
+	carregado, err := interpretarMapa("gerado:"+valor, linhas)
+	if err != nil {
+		return nil, err
+	}
+	sala, err := s.montarSala(nome, carregado)
+	if err != nil {
+		return nil, err
+	}
+	sala.geracao = &opcoes
+	return sala, nil
+}
+
+// montarSala cria a sala com o mapa já carregado e a inclui no servidor.
+// Deve ser chamada com s.mu travado (ou antes de o servidor começar a atender).
+func (s *JogoServer) montarSala(nome string, carregado mapaCarregado) (*Sala, error) {
+	sala := &Sala{
+		nome:        nome,
+		arquivoMapa: carregado.origem,
+		servidor:    s,
+		estado: EstadoJogo{
+			Sala:      nome,
+			Fase:      FaseEspera,
+			Tick:      s.tick,
+			Jogadores: make(map[string]EstadoJogador),
+			Entidades: make(map[string]EstadoEntidade),
+		},
+	}
+	if err := sala.usarMapa(carregado); err != nil {
+		return nil, fmt.Errorf("%s: %w", carregado.origem, err)
+	}
+	s.salas[nome] = sala
+	return sala, nil
+}
+
+// usarMapa passa a usar o mapa carregado. Os jogadores devem estar fora do mapa
+// (posição -1, -1), pois a ocupação recomeça vazia.
+func (sala *Sala) usarMapa(carregado mapaCarregado) error {
+	sala.mapa = carregado.mapa
+	sala.linhasMapa = carregado.linhas
+	sala.novaOcupacao()
+
+	// Os pontos de spawn vêm do mapa (cabeçalho e ☺). Sem nenhum, usa a posição da configuração,
+	// ou uma célula livre sorteada se ela não for caminhável neste mapa.
+	sala.spawns = sala.mapa.Spawns
+	sala.proximoSpawn = 0
+	if len(sala.spawns) == 0 {
+		x, y, ok := sala.servidor.spawnX, sala.servidor.spawnY, true
+		if !sala.celulaLivre(x, y) {
+			x, y, ok = sala.posicaoAleatoriaLivre(x, y)
+		}
+		if !ok {
+			return fmt.Errorf("mapa sem ponto de spawn e sem célula livre para a posição de -spawn (%d, %d)",
+				sala.servidor.spawnX, sala.servidor.spawnY)
+		}
+		sala.spawns = []Posicao{{x, y}}
+	}
+	return nil
+}
+
+// regenerarMapa troca o mapa da sala por um recém-gerado, enviado aos clientes no
+// estado (EstadoJogo.Mapa). Se a geração falhar, a sala continua com o mapa atual.
+// Deve ser chamada com os jogadores fora do mapa.
+func (sala *Sala) regenerarMapa() {
+	opcoes := *sala.geracao
+	opcoes.Semente = sala.servidor.rng.Int63()
+	linhas, err := gerarMapaConferido(opcoes)
+	var carregado mapaCarregado
+	if err == nil {
+		carregado, err = interpretarMapa(sala.arquivoMapa, linhas)
+	}
+	if err == nil {
+		err = sala.usarMapa(carregado)
+	}
+	if err != nil {
+		fmt.Printf("[Servidor] Sala %s: mantendo o mapa atual, falha ao gerar um novo: %v\n", sala.nome, err)
+		return
+	}
+	sala.estado.Mapa = linhas
+	sala.estado.MapaTick = sala.servidor.tick
+	fmt.Printf("[Servidor] Sala %s: novo mapa gerado (semente %d)\n", sala.nome, opcoes.Semente)
+}
+
+// aceitamMapaGerado indica se todos os jogadores da sala acordaram RecursoMapaGerado.
+// Basta um que não o tenha para a sala inteira manter o mapa atual, inclusive para
+// quem acordou o recurso; a troca volta na primeira rodada depois que ele sai.
+func (sala *Sala) aceitamMapaGerado() bool {
+	for id := range sala.estado.Jogadores {
+		if !sala.servidor.temRecurso(id, RecursoMapaGerado) {
+			return false
+		}
+	}
+	return true
+}
+
+// mapaPedido é o mapa de um "create", carregado antes de o comando entrar na fila.
+type mapaPedido struct {
+	carregado mapaCarregado
+	falha     *Resposta // Recusa do "create", se o mapa não puder ser usado
+}
+
+// carregarMapaPedido procura o mapa pedido num "create" no diretório do mapa da sala
+// principal, lê e valida. O disco e a validação ficam fora do tick: só s.dirMapas,
+// fixo desde NovoJogoServer, é usado, e a função pode ser chamada sem s.mu travado.
+func (s *JogoServer) carregarMapaPedido(arquivo string) mapaPedido {
+	recusa := func(codigo CodigoResposta, mensagem string) mapaPedido {
+		r := respostaFalha(codigo, MotivoMapaInvalido, mensagem)
+		return mapaPedido{falha: &r}
+	}
+
+	// O cliente escolhe apenas o nome de um mapa do diretório do servidor
+	if arquivo != filepath.Base(arquivo) || filepath.Ext(arquivo) != ".txt" {
+		return recusa(CodigoInvalido, fmt.Sprintf("Mapa inválido: %q.", arquivo))
+	}
+	carregado, err := carregarMapa(filepath.Join(s.dirMapas, arquivo))
+	var erros ErrosMapa
+	if errors.As(err, &erros) {
+		return recusa(CodigoInvalido, fmt.Sprintf("Mapa %q inválido: %v", arquivo, erros))
+	}
+	if err != nil {
+		return recusa(CodigoNaoEncontrado, fmt.Sprintf("Não foi possível carregar o mapa %q.", arquivo))
+	}
+	return mapaPedido{carregado: carregado}
+}
+
+// criarSala trata a ação "create" com o mapa já carregado por carregarMapaPedido.
+// O jogador que cria a sala entra nela.
+// Deve ser chamada com s.mu travado, dentro de um tick.
+func (s *JogoServer) criarSala(clientID string, dados DadosSala, pedido mapaPedido) Resposta {
+	nome, arquivo := dados.Nome, dados.Mapa
+	if falha := s.validarNomeSala(nome); falha != nil {
+		return *falha
+	}
+	if pedido.falha != nil {
+		return *pedido.falha
+	}
+	sala, err := s.montarSala(nome, pedido.carregado)
+	if err != nil {
+		return respostaFalha(CodigoNaoEncontrado, MotivoMapaInvalido,
+			fmt.Sprintf("Não foi possível carregar o mapa %q.", arquivo))
+	}
+	fmt.Printf("[Servidor] Sala criada: %s (%s)\n", nome, sala.arquivoMapa)
+
+	if !s.trocarSala(clientID, sala) {
+		delete(s.salas, nome)
+		return recusaSalaCheia(sala)
+	}
+	return respostaOK(fmt.Sprintf("Sala %s criada com o mapa %s.", nome, arquivo))
+}
+
+// entrarSala trata a ação "join" para a sala informada.
+// Deve ser chamada com s.mu travado, dentro de um tick.
+func (s *JogoServer) entrarSala(clientID, nome string) Resposta {
+	sala, ok := s.salas[strings.TrimSpace(nome)]
+	if !ok {
+		return respostaFalha(CodigoNaoEncontrado, MotivoSalaNaoEncontrada, fmt.Sprintf("Sala %q não encontrada.", nome))
+	}
+	if s.salaDe[clientID] == sala {
+		return respostaOK(fmt.Sprintf("Você já está na sala %s.", sala.nome))
+	}
+	if !s.trocarSala(clientID, sala) {
+		return recusaSalaCheia(sala)
+	}
+	return respostaOK(fmt.Sprintf("Entrou na sala %s.", sala.nome))
+}
+
+// trocarSala move o jogador para outra sala, onde ele recomeça num ponto de spawn.
+// Retorna false, com o jogador ainda na sala de origem, se não houver célula livre
+// no destino. Deve ser chamada com s.mu travado.
+func (s *JogoServer) trocarSala(clientID string, destino *Sala) bool {
+	jogador := EstadoJogador{Vidas: 3}
+	origem, estava := s.salaDe[clientID]
+	if estava {
+		jogador = origem.estado.Jogadores[clientID]
+	}
+	if !destino.adicionarJogador(clientID, jogador.Nome, jogador.UltimoComando) {
+		return false
+	}
+	if estava {
+		origem.retirarJogador(clientID)
+		origem.anunciar(fmt.Sprintf("%s foi para a sala %s.", jogador.Nome, destino.nome))
+		origem.registrarEvento(EventoJogadorSaiu, clientID, jogador.X, jogador.Y)
+	}
+	destino.anunciar(fmt.Sprintf("%s entrou na sala.", jogador.Nome))
+	novo := destino.estado.Jogadores[clientID]
+	destino.registrarEvento(EventoJogadorEntrou, clientID, novo.X, novo.Y)
+	return true
+}
+
+// adicionarJogador coloca um jogador num ponto de spawn livre da sala. Retorna false,
+// sem alterar a sala, se não houver célula livre.
+func (sala *Sala) adicionarJogador(clientID, nome string, ultimoComando int) bool {
+	x, y, ok := sala.escolherSpawn()
+	if !ok {
+		return false
+	}
+	sala.definirJogador(clientID, EstadoJogador{
+		Nome:          nome,
+		X:             x,
+		Y:             y,
+		Vidas:         3,
+		UltimoComando: ultimoComando,
+	})
+	sala.servidor.salaDe[clientID] = sala
+	return true
+}
+
+// escolherSpawn retorna um ponto de spawn livre, percorrendo-os em rodízio para
+// espalhar os jogadores. Se todos estiverem ocupados, sorteia uma célula livre
+// a partir do primeiro. Retorna false se não houver célula livre no mapa.
+func (sala *Sala) escolherSpawn() (int, int, bool) {
+	for i := range sala.spawns {
+		n := (sala.proximoSpawn + i) % len(sala.spawns)
+		if p := sala.spawns[n]; sala.celulaLivre(p.X, p.Y) {
+			sala.proximoSpawn = (n + 1) % len(sala.spawns)
+			return p.X, p.Y, true
+		}
+	}
+	return sala.posicaoAleatoriaLivre(sala.spawns[0].X, sala.spawns[0].Y)
+}
+
+// recusaSalaCheia é a resposta a quem não pode entrar ou reiniciar por falta de célula livre.
+func recusaSalaCheia(sala *Sala) Resposta {
+	return respostaFalha(CodigoRecusado, MotivoSemCelulaLivre,
+		fmt.Sprintf("Não há célula livre no mapa da sala %s.", sala.nome))
+}
+
+// listarSalas retorna as salas existentes em ordem de nome. Deve ser chamada com s.mu travado.
+func (s *JogoServer) listarSalas() []InfoSala {
+	salas := make([]InfoSala, 0, len(s.salas))
+	for _, nome := range idsOrdenados(s.salas) {
+		sala := s.salas[nome]
+		salas = append(salas, InfoSala{
+			Nome:      sala.nome,
+			Mapa:      filepath.Base(sala.arquivoMapa),
+			Jogadores: len(sala.estado.Jogadores),
+		})
+	}
+	return salas
+}
+
+// coletarSalas remove as salas criadas por jogadores que ficaram vazias.
+// Deve ser chamada com s.mu travado, ao final de um tick.
+func (s *JogoServer) coletarSalas() {
+	for _, nome := range idsOrdenados(s.salas) {
+		sala := s.salas[nome]
+		if !sala.permanente && len(sala.estado.Jogadores) == 0 {
+			delete(s.salas, nome)
+			fmt.Printf("[Servidor] Sala removida (vazia): %s\n", nome)
+		}
+	}
+}
+
+// validarNomeSala verifica se o nome pode ser usado por uma nova sala.
+// Retorna a resposta de recusa, ou nil se o nome for válido. Deve ser chamada com s.mu travado.
+func (s *JogoServer) validarNomeSala(nome string) *Resposta {
+	recusa := func(codigo CodigoResposta, motivo, mensagem string) *Resposta {
+		r := respostaFalha(codigo, motivo, mensagem)
+		return &r
+	}
+	if nome == "" {
+		return recusa(CodigoInvalido, MotivoSalaInvalida, "Nome de sala vazio.")
+	}
+	if len([]rune(nome)) > maxTamanhoNomeSala {
+		return recusa(CodigoInvalido, MotivoSalaInvalida,
+			fmt.Sprintf("Nome de sala muito longo (máximo de %d caracteres).", maxTamanhoNomeSala))
+	}
+	for _, r := range nome {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return recusa(CodigoInvalido, MotivoSalaInvalida, "Nome de sala contém caracteres inválidos.")
+		}
+	}
+	for existente := range s.salas {
+		if strings.EqualFold(existente, nome) {
+			return recusa(CodigoConflito, MotivoSalaExistente, fmt.Sprintf("A sala %q já existe.", existente))
+		}
+	}
+	return nil
+}
+
+// registrarEvento acrescenta um acontecimento aos eventos da sala no tick atual,
+// devolvidos nas respostas dos comandos desse tick. O jogador envolvido aparece pelo
+// identificador público.
+func (sala *Sala) registrarEvento(tipo, clientID string, x, y int) {
+	sala.eventos = append(sala.eventos, Evento{Tipo: tipo, Jogador: sala.servidor.idPublico(clientID), X: x, Y: y})
+}
+
+// enviarChat publica a mensagem do jogador como aviso da sala e como EventoChat.
+func (sala *Sala) enviarChat(clientID string, jogador EstadoJogador, texto string) {
+	sala.anunciar(fmt.Sprintf("%s: %s", jogador.Nome, texto))
+	sala.eventos = append(sala.eventos, Evento{Tipo: EventoChat, Jogador: sala.servidor.idPublico(clientID),
+		X: jogador.X, Y: jogador.Y, Texto: texto})
+}
+
+// anunciar publica uma mensagem de status para todos os jogadores da sala.
+func (sala *Sala) anunciar(mensagem string) {
+	sala.estado.Aviso = mensagem
+	sala.estado.AvisoTick = sala.servidor.tick
+}
+
+// copiaEstado retorna uma cópia independente do estado da sala, segura para ser
+// serializada fora do lock, com os jogadores pelo identificador público: é a forma
+// publicada e guardada no histórico.
+func (sala *Sala) copiaEstado() EstadoJogo {
+	copia := EstadoJogo{
+		Sala:      sala.estado.Sala,
+		Fase:      sala.estado.Fase,
+		Contagem:  sala.estado.Contagem,
+		Tick:      sala.estado.Tick,
+		Aviso:     sala.estado.Aviso,
+		AvisoTick: sala.estado.AvisoTick,
+		Mapa:      sala.estado.Mapa,
+		MapaTick:  sala.estado.MapaTick,
+		Jogadores: make(map[string]EstadoJogador, len(sala.estado.Jogadores)),
+		Entidades: make(map[string]EstadoEntidade, len(sala.estado.Entidades)),
+	}
+	for id, j := range sala.estado.Jogadores {
+		copia.Jogadores[sala.servidor.idPublico(id)] = j
+	}
+	for id, e := range sala.estado.Entidades {
+		copia.Entidades[id] = e
+	}
+	return copia
+}
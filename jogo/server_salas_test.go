@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSalaSemSpawnUsaConfiguracao(t *testing.T) {
+	mapa := []string{
+		"▤▤▤▤▤",
+		"▤   ▤",
+		"▤ ▤ ▤",
+		"▤▤▤▤▤",
+	}
+	casos := []struct {
+		nome         string
+		spawnX       int
+		spawnY       int
+		esperaConfig bool // O jogador nasce na posição da configuração
+	}{
+		{"posição livre", 3, 2, true},
+		{"posição na parede", 2, 2, false},
+		{"posição fora do mapa", 9, 9, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			arquivo := filepath.Join(t.TempDir(), "sem_spawn.txt")
+			if err := os.WriteFile(arquivo, []byte(strings.Join(mapa, "\n")+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg := configPadrao()
+			cfg.Semente = 1
+			cfg.Mapa = arquivo
+			cfg.SpawnX, cfg.SpawnY = c.spawnX, c.spawnY
+			s, err := NovoJogoServer(cfg)
+			if err != nil {
+				t.Fatalf("NovoJogoServer: %v", err)
+			}
+			r := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+			if !r.Ok() {
+				t.Fatalf("registro recusado: %v/%s", r.Codigo, r.Motivo)
+			}
+			sala := s.salas[SalaPrincipal]
+			j := sala.estado.Jogadores[r.ClientID]
+			if naConfig := j.X == c.spawnX && j.Y == c.spawnY; naConfig != c.esperaConfig {
+				t.Errorf("jogador em (%d, %d); na posição da configuração: %v, esperado %v", j.X, j.Y, naConfig, c.esperaConfig)
+			}
+			if !jogoPodeMoverPara(&sala.mapa, j.X, j.Y) {
+				t.Errorf("jogador em célula bloqueada (%d, %d)", j.X, j.Y)
+			}
+		})
+	}
+}
+
+// comandoSala monta um "create" ou um "join" do jogador registrado em r.
+func comandoSala(r Resposta, seq int, acao, nome, mapa string) Comando {
+	return Comando{ClientID: r.ClientID, Token: r.Token, SequenceNumber: seq, Acao: acao,
+		Sala: &DadosSala{Nome: nome, Mapa: mapa}}
+}
+
+// jogadoresDaSala retorna os nomes dos jogadores da sala em ordem.
+func jogadoresDaSala(sala *Sala) []string {
+	var nomes []string
+	for _, id := range idsOrdenados(sala.estado.Jogadores) {
+		nomes = append(nomes, sala.estado.Jogadores[id].Nome)
+	}
+	sort.Strings(nomes)
+	return nomes
+}
+
+func TestSalaIsolada(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+	cris := s.executarTeste(registroTeste(1, "Cris", "nonce-cris"))[0]
+
+	// Ana cria a arena e Bia entra nela; Cris fica na principal
+	if r := s.executarTeste(comandoSala(ana, 2, "create", "arena", "maze.txt"))[0]; !r.Ok() {
+		t.Fatalf("create recusado: %v/%s %q", r.Codigo, r.Motivo, r.Mensagem)
+	}
+	if r := s.executarTeste(comandoSala(bia, 2, "join", "arena", ""))[0]; !r.Ok() {
+		t.Fatalf("join recusado: %v/%s %q", r.Codigo, r.Motivo, r.Mensagem)
+	}
+
+	principal, arena := s.salas[SalaPrincipal], s.salas["arena"]
+	if arena == nil {
+		t.Fatal("sala arena não existe")
+	}
+	if got := jogadoresDaSala(principal); !reflect.DeepEqual(got, []string{"Cris"}) {
+		t.Errorf("principal = %v, esperado [Cris]", got)
+	}
+	if got := jogadoresDaSala(arena); !reflect.DeepEqual(got, []string{"Ana", "Bia"}) {
+		t.Errorf("arena = %v, esperado [Ana Bia]", got)
+	}
+	if s.salaDe[cris.ClientID] != principal || s.salaDe[ana.ClientID] != arena || s.salaDe[bia.ClientID] != arena {
+		t.Error("salaDe não acompanha a sala de cada jogador")
+	}
+	if arena.linhasMapa[1] == principal.linhasMapa[1] {
+		t.Error("a arena usa o mesmo mapa da principal")
+	}
+
+	// O chat e o estado de uma sala não chegam à outra
+	chat := Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 3, Acao: "chat", Chat: &DadosChat{Texto: "bem-vindos à arena"}}
+	r := s.executarTeste(chat)[0]
+	if !r.Ok() || !strings.Contains(arena.estado.Aviso, "bem-vindos") {
+		t.Fatalf("chat na arena: %v %q, aviso %q", r.Codigo, r.Mensagem, arena.estado.Aviso)
+	}
+	if strings.Contains(principal.estado.Aviso, "bem-vindos") {
+		t.Errorf("chat da arena apareceu na principal: %q", principal.estado.Aviso)
+	}
+	for _, e := range r.Eventos {
+		if e.Jogador == s.idPublico(cris.ClientID) {
+			t.Errorf("evento de Cris na resposta de Ana: %+v", e)
+		}
+	}
+	if _, ok := r.Delta.Jogadores[s.idPublico(cris.ClientID)]; ok {
+		t.Error("o estado da arena traz Cris, que está na principal")
+	}
+}
+
+func TestSalaColetaVazias(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+	s.executarTeste(comandoSala(ana, 2, "create", "arena", "maze.txt"), comandoSala(bia, 2, "create", "torre", "mapa.txt"))
+	if len(s.salas) != 3 {
+		t.Fatalf("salas = %v, esperado principal, arena e torre", idsOrdenados(s.salas))
+	}
+
+	// Ana volta para a principal: a arena, vazia, é removida no fim do tick;
+	// a torre, com Bia, continua
+	s.executarTeste(comandoSala(ana, 3, "join", SalaPrincipal, ""))
+	if _, ok := s.salas["arena"]; ok {
+		t.Error("a arena vazia não foi removida")
+	}
+	if _, ok := s.salas["torre"]; !ok {
+		t.Error("a torre, com Bia, foi removida")
+	}
+
+	// A principal fica mesmo vazia; a torre sai com Bia
+	s.executarTeste(
+		Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 4, Acao: "leave"},
+		Comando{ClientID: bia.ClientID, Token: bia.Token, SequenceNumber: 3, Acao: "leave"})
+	if got := idsOrdenados(s.salas); !reflect.DeepEqual(got, []string{SalaPrincipal}) {
+		t.Errorf("salas = %v, esperado só a principal", got)
+	}
+}
@@ -0,0 +1,208 @@
+// server_sessao.go - Ciclo de vida das sessões dos jogadores
+// Cada chamada de BuscarEstado ou ExecutarComando conta como heartbeat; jogadores
+// que ficam sem contato por mais que o timeout configurado são removidos, assim
+// como os que saem explicitamente com a ação "leave". Entradas e saídas são
+// anunciadas a todos pelo aviso publicado em EstadoJogo.
+//
+// O ClientID e o token identificam a sessão e só o próprio jogador os conhece: todo
+// comando deve trazer os dois. No estado e nos eventos, vistos pelos demais jogadores
+// e pelos espectadores, o jogador aparece apenas pelo identificador público.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TimeoutSessaoPadrao é o tempo sem contato após o qual um jogador é removido.
+const TimeoutSessaoPadrao = 10 * time.Second
+
+// Tamanho máximo, em caracteres, do nome escolhido pelo jogador
+const maxTamanhoNome = 16
+
+// registrarJogador trata a ação "register". Comando.Registro traz o nome desejado; o servidor
+// atribui um ClientID opaco e único, um token de sessão e um identificador público. Um
+// comando com o token de uma sessão ainda ativa retoma essa sessão em vez de criar outra.
+// Deve ser chamada com s.mu travado, dentro de um tick.
+func (s *JogoServer) registrarJogador(comando *Comando) Resposta {
+	// Retomada de sessão existente
+	if comando.Token != "" {
+		if id, ok := s.tokens[comando.Token]; ok {
+			sala := s.salaDe[id]
+			jogador := sala.estado.Jogadores[id]
+			jogador.UltimoComando = comando.SequenceNumber
+			sala.definirJogador(id, jogador)
+			s.registrarContato(id)
+			resposta := respostaOK("Sessão retomada.")
+			resposta.ClientID, resposta.Token, resposta.JogadorID = id, comando.Token, s.publicos[id]
+			resposta.Recursos = s.acordarRecursos(id, comando.Registro.Recursos)
+			return resposta
+		}
+	}
+
+	nome, falha := s.validarNome(comando.Registro.Nome)
+	if falha != nil {
+		return *falha
+	}
+
+	id := "j-" + novoIdentificador(8)
+	principal := s.salas[SalaPrincipal]
+	if !principal.adicionarJogador(id, nome, comando.SequenceNumber) {
+		return recusaSalaCheia(principal)
+	}
+	token := novoIdentificador(16)
+	s.tokens[token] = id
+	s.tokensPorID[id] = token
+	s.publicos[id] = "p-" + novoIdentificador(8)
+	s.registros++
+	s.ordemRegistro[id] = s.registros
+	s.registrarContato(id)
+	principal.anunciar(fmt.Sprintf("%s entrou no jogo.", nome))
+	novo := principal.estado.Jogadores[id]
+	principal.registrarEvento(EventoJogadorEntrou, id, novo.X, novo.Y)
+
+	resposta := respostaOK(fmt.Sprintf("Jogador registrado com sucesso como %s.", nome))
+	resposta.ClientID, resposta.Token, resposta.JogadorID = id, token, s.publicos[id]
+	resposta.Recursos = s.acordarRecursos(id, comando.Registro.Recursos)
+	return resposta
+}
+
+// recusaToken recusa os comandos que citam a sessão de um jogador sem o token dela:
+// o ClientID sozinho não autoriza nada. O "register" confere o token ao retomar a
+// sessão, e um ClientID sem sessão é recusado adiante como não registrado.
+// Retorna nil se o comando pode seguir. Deve ser chamada com s.mu travado.
+func (s *JogoServer) recusaToken(comando *Comando) *Resposta {
+	if comando.Acao == "register" || s.sessaoValida(comando.ClientID, comando.Token) {
+		return nil
+	}
+	if _, ok := s.tokensPorID[comando.ClientID]; !ok {
+		return nil
+	}
+	r := respostaFalha(CodigoRecusado, MotivoTokenInvalido, "Token de sessão inválido.")
+	return &r
+}
+
+// sessaoValida indica se o token é o da sessão ativa do jogador. Deve ser chamada com s.mu travado.
+func (s *JogoServer) sessaoValida(clientID, token string) bool {
+	esperado, ok := s.tokensPorID[clientID]
+	return ok && token == esperado
+}
+
+// idPublico retorna o identificador público do jogador, usado no estado e nos eventos.
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) idPublico(clientID string) string {
+	return s.publicos[clientID]
+}
+
+// acordarRecursos guarda os recursos que o jogador pediu e o servidor suporta e
+// os retorna. Uma sessão retomada renegocia, pois o cliente pode ter sido atualizado.
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) acordarRecursos(clientID string, pedidos []string) []string {
+	aceitos := negociarRecursos(pedidos)
+	s.recursos[clientID] = make(map[string]bool, len(aceitos))
+	for _, recurso := range aceitos {
+		s.recursos[clientID][recurso] = true
+	}
+	return aceitos
+}
+
+// temRecurso indica se o recurso foi acordado com o jogador. Deve ser chamada com s.mu travado.
+func (s *JogoServer) temRecurso(clientID, recurso string) bool {
+	return s.recursos[clientID][recurso]
+}
+
+// validarNome normaliza o nome pedido e garante que ele é válido e não está em uso.
+// Sem nome, o servidor escolhe um "Jogador-N" livre. Se o nome for recusado, retorna
+// a resposta de recusa. Deve ser chamada com s.mu travado.
+func (s *JogoServer) validarNome(pedido string) (string, *Resposta) {
+	recusa := func(codigo CodigoResposta, motivo, mensagem string) (string, *Resposta) {
+		r := respostaFalha(codigo, motivo, mensagem)
+		return "", &r
+	}
+	nome := strings.TrimSpace(pedido)
+	if nome == "" {
+		for n := len(s.salaDe) + 1; ; n++ {
+			nome = fmt.Sprintf("Jogador-%d", n)
+			if !s.nomeEmUso(nome) {
+				return nome, nil
+			}
+		}
+	}
+	if len([]rune(nome)) > maxTamanhoNome {
+		return recusa(CodigoInvalido, MotivoNomeInvalido, fmt.Sprintf("Nome muito longo (máximo de %d caracteres).", maxTamanhoNome))
+	}
+	for _, r := range nome {
+		if !unicode.IsPrint(r) {
+			return recusa(CodigoInvalido, MotivoNomeInvalido, "Nome contém caracteres inválidos.")
+		}
+	}
+	if s.nomeEmUso(nome) {
+		return recusa(CodigoConflito, MotivoNomeEmUso, fmt.Sprintf("O nome %q já está em uso.", nome))
+	}
+	return nome, nil
+}
+
+// nomeEmUso compara o nome, sem diferenciar maiúsculas, com os dos jogadores de todas as salas.
+func (s *JogoServer) nomeEmUso(nome string) bool {
+	for id, sala := range s.salaDe {
+		if strings.EqualFold(sala.estado.Jogadores[id].Nome, nome) {
+			return true
+		}
+	}
+	return false
+}
+
+// novoIdentificador gera uma sequência aleatória de n bytes em hexadecimal.
+func novoIdentificador(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// registrarContato marca o tick atual como último contato do jogador.
+// Deve ser chamada com s.mu travado.
+func (s *JogoServer) registrarContato(clientID string) {
+	if _, ok := s.salaDe[clientID]; ok {
+		s.ultimoContato[clientID] = s.tick
+	}
+}
+
+// removerJogador tira o jogador do estado e anuncia a saída. O cache de respostas
+// fica, para que o reenvio do "leave" receba a resposta original; ele expira por
+// inatividade (expirarRespostas). Deve ser chamada com s.mu travado.
+func (s *JogoServer) removerJogador(clientID string, motivo string) {
+	sala, ok := s.salaDe[clientID]
+	if !ok {
+		return
+	}
+	jogador := sala.estado.Jogadores[clientID]
+	sala.retirarJogador(clientID)
+	sala.registrarEvento(EventoJogadorSaiu, clientID, jogador.X, jogador.Y)
+	delete(s.salaDe, clientID)
+	delete(s.ultimoContato, clientID)
+	delete(s.tokens, s.tokensPorID[clientID])
+	delete(s.tokensPorID, clientID)
+	delete(s.publicos, clientID)
+	delete(s.recursos, clientID)
+	delete(s.ordemRegistro, clientID)
+	sala.anunciar(fmt.Sprintf("%s %s.", jogador.Nome, motivo))
+}
+
+// expirarSessoes remove os jogadores sem contato há mais que o timeout.
+// Deve ser chamada com s.mu travado, dentro de um tick.
+func (s *JogoServer) expirarSessoes() {
+	limite := s.ticksPara(s.timeoutSessao)
+	for _, id := range idsOrdenados(s.salaDe) {
+		if s.tick-s.ultimoContato[id] > limite {
+			fmt.Printf("[Servidor] Sessão expirada: %s (%s)\n", id, s.salaDe[id].estado.Jogadores[id].Nome)
+			s.removerJogador(id, "desconectou (inatividade)")
+			s.descartarRespostas(id)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessaoExigeToken(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+
+	casos := []struct {
+		nome  string
+		token string
+	}{
+		{"sem token", ""},
+		{"token inventado", "0123456789abcdef"},
+		{"token de outro jogador", bia.Token},
+	}
+	for i, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			seq := 10 + i
+			comandos := []Comando{
+				{ClientID: ana.ClientID, Token: c.token, SequenceNumber: seq, Acao: "leave"},
+				{ClientID: ana.ClientID, Token: c.token, SequenceNumber: seq, Acao: "ready"},
+				{ClientID: ana.ClientID, Token: c.token, SequenceNumber: seq, Acao: "move", Movimento: &DadosMovimento{Direcao: "d"}},
+			}
+			for _, comando := range comandos {
+				r := s.executarTeste(comando)[0]
+				if r.Codigo != CodigoRecusado || r.Motivo != MotivoTokenInvalido {
+					t.Errorf("%s = %v/%s, esperado %v/%s", comando.Acao, r.Codigo, r.Motivo, CodigoRecusado, MotivoTokenInvalido)
+				}
+			}
+			var r Resposta
+			s.BuscarEstado(&Comando{ClientID: ana.ClientID, Token: c.token, Versao: VersaoProtocolo}, &r)
+			if r.Motivo != MotivoTokenInvalido {
+				t.Errorf("BuscarEstado = %v/%s, esperado %s", r.Codigo, r.Motivo, MotivoTokenInvalido)
+			}
+			if _, ok := s.salaDe[ana.ClientID]; !ok {
+				t.Fatal("jogador removido por um comando sem o seu token")
+			}
+		})
+	}
+
+	// Com o token certo, o mesmo comando é aceito
+	r := s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 20, Acao: "leave"})[0]
+	if !r.Ok() {
+		t.Fatalf("leave com o token da sessão = %v/%s", r.Codigo, r.Motivo)
+	}
+	if _, ok := s.salaDe[ana.ClientID]; ok {
+		t.Error("jogador mantido após o leave")
+	}
+}
+
+func TestSessaoIdentificadorPublico(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+	if bia.JogadorID == "" || bia.JogadorID == bia.ClientID {
+		t.Fatalf("identificador público %q igual ao ClientID ou vazio", bia.JogadorID)
+	}
+
+	// Os demais jogadores veem Bia apenas pelo identificador público
+	estado := bia.Delta.Jogadores
+	if _, ok := estado[bia.ClientID]; ok {
+		t.Error("ClientID publicado nas chaves do estado")
+	}
+	if j, ok := estado[bia.JogadorID]; !ok || j.Nome != "Bia" {
+		t.Errorf("jogador %q ausente do estado: %+v", bia.JogadorID, estado)
+	}
+	if _, ok := estado[ana.JogadorID]; !ok {
+		t.Errorf("jogador %q ausente do estado: %+v", ana.JogadorID, estado)
+	}
+	for _, evento := range bia.Eventos {
+		if evento.Jogador == bia.ClientID || evento.Jogador == ana.ClientID {
+			t.Errorf("ClientID publicado no evento %+v", evento)
+		}
+	}
+}
+
+func TestSessaoStreamExigeToken(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+
+	casos := []struct {
+		nome   string
+		linha  string
+		aceita bool
+	}{
+		{"sem token", PrefixoStream + ana.ClientID + "\n", false},
+		{"token de outro jogador", PrefixoStream + ana.ClientID + " " + bia.Token + "\n", false},
+		{"token da sessão", PrefixoStream + ana.ClientID + " " + ana.Token + "\n", true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			servidor, cliente := net.Pipe()
+			defer cliente.Close()
+			clientID, token := lerInscricao(c.linha)
+			go s.servirStream(servidor, bufio.NewReader(servidor), clientID, token, json.NewEncoder(servidor))
+
+			// Uma inscrição aceita começa com o snapshot; uma recusada é encerrada sem dados
+			var delta DeltaEstado
+			err := json.NewDecoder(cliente).Decode(&delta)
+			if c.aceita && (err != nil || !delta.Completo) {
+				t.Errorf("inscrição recusada: %v", err)
+			}
+			if !c.aceita && err == nil {
+				t.Errorf("inscrição aceita: %+v", delta)
+			}
+		})
+	}
+}
+
+func TestSessaoStreamEncerradoAoSair(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+
+	servidor, cliente := net.Pipe()
+	defer cliente.Close()
+	go s.servirStream(servidor, bufio.NewReader(servidor), ana.ClientID, ana.Token, json.NewEncoder(servidor))
+	decoder := json.NewDecoder(cliente)
+	var delta DeltaEstado
+	if err := decoder.Decode(&delta); err != nil {
+		t.Fatalf("snapshot inicial: %v", err)
+	}
+
+	// Sem sala, o stream do jogador é encerrado em vez de ficar à espera
+	s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 2, Acao: "leave"})
+	cliente.SetReadDeadline(time.Now().Add(time.Second))
+	if err := decoder.Decode(&delta); err != io.EOF {
+		t.Errorf("stream após o leave = %v, esperado o fim da conexão", err)
+	}
+}
+
+// eventoDe procura nos eventos um do tipo informado envolvendo o jogador.
+func eventoDe(eventos []Evento, tipo, jogadorID string) bool {
+	for _, e := range eventos {
+		if e.Tipo == tipo && e.Jogador == jogadorID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSessaoExpiraPorInatividade(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	bia := s.executarTeste(registroTeste(1, "Bia", "nonce-bia"))[0]
+	heartbeat := func() {
+		var r Resposta
+		s.BuscarEstado(&Comando{ClientID: bia.ClientID, Token: bia.Token, Versao: VersaoProtocolo}, &r)
+	}
+
+	// Ana fica em silêncio; Bia mantém a sessão com heartbeats. Até completar o
+	// timeout (10s na taxa de ticks do servidor), Ana continua na sala
+	limite := uint64(TimeoutSessaoPadrao/time.Second) * uint64(s.taxaTick)
+	desde := s.ultimoContato[ana.ClientID]
+	for s.tick < desde+limite {
+		heartbeat()
+		s.executarTick()
+	}
+	if _, ok := s.salaDe[ana.ClientID]; !ok {
+		t.Fatalf("Ana removida no tick %d, antes do timeout (%d ticks)", s.tick, limite)
+	}
+
+	// No tick seguinte a sessão expira: Ana sai, com aviso e evento para Bia
+	heartbeat()
+	r := s.executarTeste(Comando{ClientID: bia.ClientID, Token: bia.Token, SequenceNumber: 2, Acao: "rooms"})[0]
+	if _, ok := s.salaDe[ana.ClientID]; ok {
+		t.Fatal("Ana mantida depois do timeout")
+	}
+	if _, ok := s.salaDe[bia.ClientID]; !ok {
+		t.Fatal("Bia removida apesar dos heartbeats")
+	}
+	if !eventoDe(r.Eventos, EventoJogadorSaiu, ana.JogadorID) {
+		t.Errorf("eventos = %+v, esperado %s de %s", r.Eventos, EventoJogadorSaiu, ana.JogadorID)
+	}
+	if aviso := s.salas[SalaPrincipal].estado.Aviso; aviso != "Ana desconectou (inatividade)." {
+		t.Errorf("aviso = %q", aviso)
+	}
+
+	// O token da sessão expirada não vale mais
+	r = s.executarTeste(Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: 2, Acao: "ready"})[0]
+	if r.Codigo != CodigoNaoRegistrado {
+		t.Errorf("comando da sessão expirada = %v/%s, esperado %v", r.Codigo, r.Motivo, CodigoNaoRegistrado)
+	}
+}
+
+func TestSessaoEventosEntradaESaida(t *testing.T) {
+	s := novoServidorTeste(t)
+	ana := s.executarTeste(registroTeste(1, "Ana", "nonce-ana"))[0]
+	consulta := func(seq int, ultimoTick uint64) Comando {
+		return Comando{ClientID: ana.ClientID, Token: ana.Token, SequenceNumber: seq, Acao: "rooms", UltimoTick: ultimoTick}
+	}
+
+	// Ana, na mesma sala, fica sabendo da entrada de Bia pelo evento e pelo aviso
+	respostas := s.executarTeste(consulta(2, ana.Delta.Tick), registroTeste(1, "Bia", "nonce-bia"))
+	vista, bia := respostas[0], respostas[1]
+	if !eventoDe(vista.Eventos, EventoJogadorEntrou, bia.JogadorID) {
+		t.Errorf("eventos = %+v, esperado %s de %s", vista.Eventos, EventoJogadorEntrou, bia.JogadorID)
+	}
+	if vista.Delta.Aviso != "Bia entrou no jogo." {
+		t.Errorf("aviso da entrada = %q", vista.Delta.Aviso)
+	}
+
+	// E da saída
+	respostas = s.executarTeste(consulta(3, vista.Delta.Tick), Comando{ClientID: bia.ClientID, Token: bia.Token, SequenceNumber: 2, Acao: "leave"})
+	vista = respostas[0]
+	if !eventoDe(vista.Eventos, EventoJogadorSaiu, bia.JogadorID) {
+		t.Errorf("eventos = %+v, esperado %s de %s", vista.Eventos, EventoJogadorSaiu, bia.JogadorID)
+	}
+	if vista.Delta.Aviso != "Bia saiu do jogo." {
+		t.Errorf("aviso da saída = %q", vista.Delta.Aviso)
+	}
+	if !reflect.DeepEqual(vista.Delta.JogadoresRemovidos, []string{bia.JogadorID}) {
+		t.Errorf("jogadores removidos = %v, esperado [%s]", vista.Delta.JogadoresRemovidos, bia.JogadorID)
+	}
+}
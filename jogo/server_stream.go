@@ -0,0 +1,264 @@
+// server_stream.go - Envio do estado por push para clientes inscritos
+// Uma conexão TCP no mesmo listener do RPC que comece com PrefixoStream vira uma
+// inscrição: o servidor passa a enviar (gob) um DeltaEstado assim que um tick
+// altera o estado da sala do jogador, sem que o cliente precise consultar BuscarEstado.
+// A linha de inscrição leva o token da sessão, como todo comando (server_sessao.go).
+// O cliente pode escrever "ACK <tick>\n" na mesma conexão para informar o último
+// tick que aplicou; o próximo delta parte desse tick.
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"strings"
+)
+
+// PrefixoStream inicia a linha de inscrição enviada pelo cliente: "INSCREVER <ClientID> <token>\n".
+const PrefixoStream = "INSCREVER "
+
+// PrefixoAck inicia as linhas de confirmação enviadas pelo cliente no stream: "ACK <tick>\n".
+const PrefixoAck = "ACK "
+
+// assinante é um cliente inscrito no stream de estado.
+// O canal aviso apenas sinaliza que há novidades; avisos acumulados são
+// agrupados e o delta é sempre calculado a partir de base.
+type assinante struct {
+	clientID string // Jogador inscrito (vazio para espectadores)
+	nomeSala string // Sala assistida por um espectador (server_espectador.go)
+	aviso    chan struct{}
+	sala     *Sala  // Sala do último delta enviado (protegido por s.mu)
+	base     uint64 // Tick a partir do qual o próximo delta é calculado (protegido por s.mu)
+	mapaTick uint64 // MapaTick do último mapa enviado a um espectador (protegido por s.mu)
+}
+
+// avisar sinaliza o assinante sem bloquear.
+func (a *assinante) avisar() {
+	select {
+	case a.aviso <- struct{}{}:
+	default:
+	}
+}
+
+// conexaoBufferizada permite entregar ao RPC uma conexão cujos primeiros bytes já foram lidos.
+type conexaoBufferizada struct {
+	*bufio.Reader
+	net.Conn
+}
+
+func (c conexaoBufferizada) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+// aceitarConexoes separa as conexões de stream (de jogadores e de espectadores,
+// server_espectador.go) das conexões RPC no mesmo listener.
+func (s *JogoServer) aceitarConexoes(listener net.Listener, servidorRPC *rpc.Server) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println("Erro ao aceitar conexão:", err)
+			return
+		}
+		go s.atenderConexao(conn, servidorRPC)
+	}
+}
+
+func (s *JogoServer) atenderConexao(conn net.Conn, servidorRPC *rpc.Server) {
+	leitor := bufio.NewReader(conn)
+	inicio, err := leitor.Peek(len(PrefixoStream))
+	espectador := strings.HasPrefix(string(inicio), PrefixoEspectador)
+	if !espectador && (err != nil || string(inicio) != PrefixoStream) {
+		servidorRPC.ServeConn(conexaoBufferizada{leitor, conn})
+		return
+	}
+
+	linha, err := leitor.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if espectador {
+		nomeSala := strings.TrimSpace(strings.TrimPrefix(linha, PrefixoEspectador))
+		s.servirEspectador(conn, leitor, nomeSala, gob.NewEncoder(conn))
+		return
+	}
+	clientID, token := lerInscricao(linha)
+	s.servirStream(conn, leitor, clientID, token, gob.NewEncoder(conn))
+}
+
+// lerInscricao extrai o ClientID e o token de uma linha "INSCREVER <ClientID> <token>".
+func lerInscricao(linha string) (clientID, token string) {
+	campos := strings.Fields(strings.TrimPrefix(linha, PrefixoStream))
+	if len(campos) > 0 {
+		clientID = campos[0]
+	}
+	if len(campos) > 1 {
+		token = campos[1]
+	}
+	return clientID, token
+}
+
+// codificadorEstado serializa os deltas do stream: gob no listener RPC,
+// JSON no gateway (server_gateway.go).
+type codificadorEstado interface {
+	Encode(v any) error
+}
+
+// servirStream inscreve o jogador no stream de estado, se o token for o da sessão e ele
+// acordou o recurso no registro: envia um snapshot inicial e, em seguida, um delta a
+// cada atualização publicada. As confirmações "ACK <tick>" são lidas de leitor.
+func (s *JogoServer) servirStream(conn io.Closer, leitor *bufio.Reader, clientID, token string, enc codificadorEstado) {
+	defer conn.Close()
+
+	a := &assinante{clientID: clientID, aviso: make(chan struct{}, 1)}
+	s.mu.Lock()
+	if !s.sessaoValida(clientID, token) {
+		s.mu.Unlock()
+		fmt.Printf("[Servidor] Stream recusado (token inválido): %s\n", clientID)
+		return
+	}
+	if !s.temRecurso(clientID, RecursoStream) {
+		s.mu.Unlock()
+		fmt.Printf("[Servidor] Stream recusado (recurso não acordado): %s\n", clientID)
+		return
+	}
+	s.mu.Unlock()
+	s.servirAssinante(a, leitor, enc)
+}
+
+// servirAssinante inscreve o assinante e envia o estado da sala dele a cada
+// atualização publicada, até a conexão ser encerrada ou o assinante ficar sem sala
+// (jogador que saiu ou expirou, sala assistida que foi removida). Ao retornar, quem
+// chamou fecha a conexão.
+func (s *JogoServer) servirAssinante(a *assinante, leitor *bufio.Reader, enc codificadorEstado) {
+	nome := a.clientID
+	if a.nomeSala != "" {
+		nome = "espectador da sala " + a.nomeSala
+	}
+	s.mu.Lock()
+	s.assinantes[a] = struct{}{}
+	s.mu.Unlock()
+	fmt.Printf("[Servidor] Stream iniciado para: %s\n", nome)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.assinantes, a)
+		s.mu.Unlock()
+		fmt.Printf("[Servidor] Stream encerrado para: %s\n", nome)
+	}()
+
+	// Lê as confirmações do cliente até a conexão ser encerrada
+	fim := make(chan struct{})
+	go func() {
+		defer close(fim)
+		for {
+			linha, err := leitor.ReadString('\n')
+			if err != nil {
+				return
+			}
+			var tick uint64
+			if _, err := fmt.Sscanf(linha, PrefixoAck+"%d", &tick); err == nil {
+				s.mu.Lock()
+				a.base = tick
+				s.mu.Unlock()
+				a.avisar()
+			}
+		}
+	}()
+
+	a.avisar()
+	for {
+		select {
+		case <-fim:
+			return
+		case <-a.aviso:
+		}
+
+		s.mu.Lock()
+		sala, ok := s.salaDoAssinante(a)
+		var delta DeltaEstado
+		if ok {
+			if sala != a.sala {
+				// O jogador trocou de sala: o tick confirmado não serve de base
+				a.sala, a.base = sala, 0
+			}
+			if a.nomeSala != "" {
+				// Espectadores recebem sempre o estado completo, mas o mapa gerado só quando muda
+				delta = snapshotCompleto(sala.copiaEstado())
+				if delta.MapaTick == a.mapaTick {
+					delta.Mapa = nil
+				}
+				a.mapaTick = delta.MapaTick
+			} else {
+				delta = s.estadoPara(a.clientID, sala, a.base)
+			}
+			a.base = delta.Tick
+		}
+		s.mu.Unlock()
+
+		if !ok {
+			fmt.Printf("[Servidor] Stream sem sala: %s\n", nome)
+			return
+		}
+		if deltaVazio(delta) {
+			continue
+		}
+		if err := enc.Encode(&delta); err != nil {
+			return
+		}
+	}
+}
+
+// salaDoAssinante retorna a sala assistida por um espectador ou a sala atual do
+// jogador inscrito. Deve ser chamada com s.mu travado.
+func (s *JogoServer) salaDoAssinante(a *assinante) (*Sala, bool) {
+	if a.nomeSala != "" {
+		sala, ok := s.salas[a.nomeSala]
+		return sala, ok
+	}
+	sala, ok := s.salaDe[a.clientID]
+	return sala, ok
+}
+
+// publicarEstado registra o estado da sala no histórico e avisa os assinantes que estão
+// (ou estavam) nela, se ele mudou desde a última publicação.
+// Deve ser chamada com s.mu travado, ao final de um tick.
+func (s *JogoServer) publicarEstado(sala *Sala) {
+	atual := sala.copiaEstado()
+	if estadosIguais(sala.ultimoPublicado, atual) {
+		return
+	}
+	sala.ultimoPublicado = atual
+	sala.registrarHistorico()
+
+	for a := range s.assinantes {
+		if a.sala == sala || a.nomeSala == sala.nome || s.salaDe[a.clientID] == sala {
+			a.avisar()
+		}
+	}
+}
+
+// estadosIguais compara jogadores, entidades, aviso, fase e mapa de dois estados, ignorando o tick.
+func estadosIguais(a, b EstadoJogo) bool {
+	if len(a.Jogadores) != len(b.Jogadores) || len(a.Entidades) != len(b.Entidades) {
+		return false
+	}
+	if a.AvisoTick != b.AvisoTick || a.Fase != b.Fase || a.Contagem != b.Contagem || a.MapaTick != b.MapaTick {
+		return false
+	}
+	for id, j := range a.Jogadores {
+		if outro, ok := b.Jogadores[id]; !ok || outro != j {
+			return false
+		}
+	}
+	for id, e := range a.Entidades {
+		if outra, ok := b.Entidades[id]; !ok || outra != e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+// server_tick.go - Simulação autoritativa do servidor em ticks de taxa fixa
+// Os comandos recebidos por RPC são enfileirados e aplicados apenas dentro de um tick,
+// em ordem determinística, seguidos do avanço dos elementos autônomos.
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// TaxaTickPadrao é a quantidade de ticks por segundo usada quando nenhuma é informada.
+const TaxaTickPadrao = 20
+
+// comandoPendente é um comando aguardando o próximo tick, junto com o canal
+// pelo qual a resposta é devolvida ao handler RPC.
+type comandoPendente struct {
+	comando  Comando
+	resposta chan Resposta
+	mapa     mapaPedido // Mapa de um "create", carregado antes de entrar na fila (server_salas.go)
+}
+
+// novoPendente prepara o comando para a fila. O que não depende do estado do jogo
+// é feito aqui, sem s.mu travado: o "create" chega ao tick com o mapa da nova sala
+// já lido e validado, e o tick não espera pelo disco.
+func (s *JogoServer) novoPendente(comando Comando, resposta chan Resposta) comandoPendente {
+	pendente := comandoPendente{comando: comando, resposta: resposta}
+	if comando.Acao == "create" && comando.Sala != nil {
+		pendente.mapa = s.carregarMapaPedido(comando.Sala.Mapa)
+	}
+	return pendente
+}
+
+// loopTicks executa a simulação na taxa configurada.
+func (s *JogoServer) loopTicks() {
+	ticker := time.NewTicker(time.Second / time.Duration(s.taxaTick))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.executarTick()
+	}
+}
+
+// executarTick avança a simulação em um tick:
+// aplica os comandos da fila, move os elementos autônomos de cada sala e responde aos clientes.
+func (s *JogoServer) executarTick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tick++
+	fila := s.fila
+	s.fila = nil
+	for _, sala := range s.salas {
+		sala.eventos = nil
+	}
+
+	// Ordem determinística: por cliente, numa ordem que varia a cada tick
+	// (ordemNoTick, server_colisao.go), e, dentro do cliente, por número de sequência
+	ordem := s.ordemNoTick(fila)
+	prioridade := func(clientID string) int {
+		if p, ok := ordem[clientID]; ok {
+			return p
+		}
+		return len(ordem)
+	}
+	sort.SliceStable(fila, func(i, j int) bool {
+		a, b := fila[i].comando.ClientID, fila[j].comando.ClientID
+		if a != b {
+			pa, pb := prioridade(a), prioridade(b)
+			if pa != pb {
+				return pa < pb
+			}
+			return a < b
+		}
+		return fila[i].comando.SequenceNumber < fila[j].comando.SequenceNumber
+	})
+
+	// Comandos sem o token da sessão citada são recusados antes de tudo (server_sessao.go).
+	// Retransmissões recebem a resposta original, guardada no cache. Cópias do mesmo
+	// comando na mesma fila (reenvio antes da resposta) reaproveitam a primeira execução.
+	type chaveComando struct {
+		remetente string // chaveCache, server_respostas.go
+		token     string
+		nome      string // Nome pedido, nos registros: outro nome com o mesmo nonce não é cópia
+		seq       int
+	}
+	respostas := make([]Resposta, len(fila))
+	pronta := make([]bool, len(fila)) // Resposta do cache ou recusa do token: não é guardada de novo
+	origem := make([]int, len(fila))
+	vistos := make(map[chaveComando]int)
+	salaAntes := make([]*Sala, len(fila))
+	for i := range fila {
+		origem[i] = -1
+		salaAntes[i] = s.salaDe[fila[i].comando.ClientID]
+		if falha := s.recusaToken(&fila[i].comando); falha != nil {
+			respostas[i], pronta[i] = *falha, true
+			continue
+		}
+		chave := chaveComando{chaveCache(&fila[i].comando), fila[i].comando.Token, nomeRegistro(&fila[i].comando), fila[i].comando.SequenceNumber}
+		if j, ok := vistos[chave]; ok && chave.remetente != "" {
+			origem[i] = j
+			continue
+		}
+		vistos[chave] = i
+		if resposta, ok := s.respostaEmCache(&fila[i].comando); ok {
+			respostas[i], pronta[i] = resposta, true
+			continue
+		}
+		respostas[i] = s.aplicarComando(&fila[i])
+	}
+
+	s.expirarSessoes()
+	for _, nome := range idsOrdenados(s.salas) {
+		sala := s.salas[nome]
+		sala.avancarEspera()
+		if sala.estado.Fase == FaseJogo {
+			sala.avancarElementos()
+		}
+		sala.estado.Tick = s.tick
+		s.publicarEstado(sala)
+	}
+	s.coletarSalas()
+	s.expirarRespostas()
+
+	for i, pendente := range fila {
+		switch {
+		case origem[i] >= 0:
+			// A fila está ordenada: a primeira cópia já foi respondida
+			respostas[i] = respostas[origem[i]]
+		case !pronta[i]:
+			s.anexarEstado(&pendente.comando, &respostas[i], salaAntes[i])
+			s.guardarResposta(&pendente.comando, respostas[i])
+		}
+		pendente.resposta <- respostas[i]
+	}
+}
+
+// ticksPara converte uma duração em quantidade de ticks (no mínimo um).
+func (s *JogoServer) ticksPara(d time.Duration) uint64 {
+	n := uint64(d * time.Duration(s.taxaTick) / time.Second)
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// anexarEstado inclui na resposta o estado e, se acordados, os eventos do tick da sala do jogador. Quem acabou de entrar
+// numa sala (registro, "join" ou "create") recebe também o mapa dela e um snapshot
+// completo, já que o tick confirmado se refere à sala anterior. Deve ser chamada com s.mu travado.
+func (s *JogoServer) anexarEstado(comando *Comando, resposta *Resposta, salaAntes *Sala) {
+	id := comando.ClientID
+	if resposta.ClientID != "" {
+		id = resposta.ClientID
+	}
+	sala, ok := s.salaDe[id]
+	if !ok {
+		return
+	}
+	if s.temRecurso(id, RecursoEventos) {
+		resposta.Eventos = append([]Evento(nil), sala.eventos...)
+	}
+	if sala != salaAntes || comando.Acao == "register" {
+		resposta.Sala = sala.nome
+		resposta.Mapa = sala.linhasMapa
+		resposta.Delta = snapshotCompleto(sala.copiaEstado())
+		return
+	}
+	resposta.Delta = s.estadoPara(id, sala, comando.UltimoTick)
+}
+
+// estadoPara retorna o estado da sala a partir do tick confirmado pelo jogador: um delta,
+// se o recurso foi acordado, ou um snapshot completo. Deve ser chamada com s.mu travado.
+func (s *JogoServer) estadoPara(clientID string, sala *Sala, tick uint64) DeltaEstado {
+	if !s.temRecurso(clientID, RecursoDelta) {
+		return snapshotCompleto(sala.copiaEstado())
+	}
+	return sala.deltaDesde(tick)
+}
+
+// idsOrdenados retorna as chaves do mapa em ordem, para iterações determinísticas.
+func idsOrdenados[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,199 @@
+// server_websocket.go - WebSocket (RFC 6455) mínimo para o gateway JSON
+// Implementa apenas o lado do servidor: o handshake de upgrade e o enquadramento
+// das mensagens. Cada Write vira uma mensagem de texto; Read devolve o conteúdo
+// das mensagens recebidas em sequência, como um stream, o que basta para os
+// codificadores JSON do gateway (server_gateway.go). Uma mensagem fragmentada só
+// é entregue quando chega o último fragmento. Ping, pong e close são tratados
+// internamente.
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// GUID fixo do RFC 6455 usado no cálculo de Sec-WebSocket-Accept
+const guidWebSocket = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Tamanho máximo aceito para uma mensagem recebida
+const maxMensagemWebSocket = 1 << 20
+
+// Opcodes dos quadros WebSocket
+const (
+	wsContinuacao = 0x0
+	wsTexto       = 0x1
+	wsBinario     = 0x2
+	wsFechar      = 0x8
+	wsPing        = 0x9
+	wsPong        = 0xA
+)
+
+var errQuadroInvalido = errors.New("websocket: quadro inválido")
+
+// conexaoWebSocket adapta uma conexão WebSocket a io.ReadWriteCloser.
+type conexaoWebSocket struct {
+	conn     net.Conn
+	leitor   *bufio.Reader
+	pendente []byte // Restante da mensagem em leitura
+
+	fragmentos   []byte // Fragmentos recebidos da mensagem ainda sem FIN
+	fragmentando bool   // Há uma mensagem fragmentada em andamento
+
+	escrita sync.Mutex // Serializa os quadros enviados
+	fechada bool       // Quadro de fechamento já enviado (protegido por escrita)
+}
+
+// pedidoWebSocket indica se o pedido HTTP é um upgrade para WebSocket.
+func pedidoWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// aceitarWebSocket responde ao handshake de upgrade e retorna a conexão WebSocket.
+// O pedido já foi lido de leitor.
+func aceitarWebSocket(conn net.Conn, leitor *bufio.Reader, r *http.Request) (*conexaoWebSocket, error) {
+	chave := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || chave == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		fmt.Fprint(conn, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n")
+		return nil, errors.New("websocket: handshake inválido")
+	}
+	h := sha1.New()
+	io.WriteString(h, chave+guidWebSocket)
+	aceite := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	_, err := fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", aceite)
+	if err != nil {
+		return nil, err
+	}
+	return &conexaoWebSocket{conn: conn, leitor: leitor}, nil
+}
+
+// Read devolve o conteúdo das mensagens de dados recebidas.
+func (ws *conexaoWebSocket) Read(p []byte) (int, error) {
+	for len(ws.pendente) == 0 {
+		fin, opcode, dados, err := ws.lerQuadro()
+		if err != nil {
+			return 0, err
+		}
+		switch opcode {
+		case wsTexto, wsBinario, wsContinuacao:
+			// Continuação só vale dentro de uma mensagem, e uma nova só começa após o FIN
+			if (opcode == wsContinuacao) != ws.fragmentando {
+				return 0, errQuadroInvalido
+			}
+			if len(ws.fragmentos)+len(dados) > maxMensagemWebSocket {
+				return 0, errQuadroInvalido
+			}
+			ws.fragmentos = append(ws.fragmentos, dados...)
+			ws.fragmentando = !fin
+			if fin {
+				ws.pendente, ws.fragmentos = ws.fragmentos, nil
+			}
+		case wsPing:
+			if err := ws.escreverQuadro(wsPong, dados); err != nil {
+				return 0, err
+			}
+		case wsFechar:
+			ws.escreverQuadro(wsFechar, nil)
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, ws.pendente)
+	ws.pendente = ws.pendente[n:]
+	return n, nil
+}
+
+// Write envia p como uma mensagem de texto.
+func (ws *conexaoWebSocket) Write(p []byte) (int, error) {
+	if err := ws.escreverQuadro(wsTexto, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close envia o quadro de fechamento, se ainda não enviado, e encerra a conexão.
+func (ws *conexaoWebSocket) Close() error {
+	ws.escreverQuadro(wsFechar, nil)
+	return ws.conn.Close()
+}
+
+// lerQuadro lê um quadro do cliente, que deve vir mascarado, e indica se ele é o
+// último fragmento (FIN) da mensagem. Quadros de controle não podem ser fragmentados.
+func (ws *conexaoWebSocket) lerQuadro() (bool, byte, []byte, error) {
+	var cabecalho [2]byte
+	if _, err := io.ReadFull(ws.leitor, cabecalho[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := cabecalho[0]&0x80 != 0
+	opcode := cabecalho[0] & 0x0F
+	if cabecalho[1]&0x80 == 0 || (opcode&0x8 != 0 && !fin) {
+		return false, 0, nil, errQuadroInvalido
+	}
+
+	tamanho := uint64(cabecalho[1] & 0x7F)
+	switch tamanho {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(ws.leitor, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		tamanho = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(ws.leitor, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		tamanho = binary.BigEndian.Uint64(b[:])
+	}
+	if tamanho > maxMensagemWebSocket {
+		return false, 0, nil, errQuadroInvalido
+	}
+
+	var mascara [4]byte
+	if _, err := io.ReadFull(ws.leitor, mascara[:]); err != nil {
+		return false, 0, nil, err
+	}
+	dados := make([]byte, tamanho)
+	if _, err := io.ReadFull(ws.leitor, dados); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range dados {
+		dados[i] ^= mascara[i%4]
+	}
+	return fin, opcode, dados, nil
+}
+
+// escreverQuadro envia um quadro completo (FIN) sem máscara, como exige o RFC para o servidor.
+func (ws *conexaoWebSocket) escreverQuadro(opcode byte, dados []byte) error {
+	ws.escrita.Lock()
+	defer ws.escrita.Unlock()
+	if ws.fechada {
+		return net.ErrClosed
+	}
+	if opcode == wsFechar {
+		ws.fechada = true
+	}
+
+	quadro := []byte{0x80 | opcode}
+	switch n := len(dados); {
+	case n < 126:
+		quadro = append(quadro, byte(n))
+	case n <= 0xFFFF:
+		quadro = append(quadro, 126)
+		quadro = binary.BigEndian.AppendUint16(quadro, uint16(n))
+	default:
+		quadro = append(quadro, 127)
+		quadro = binary.BigEndian.AppendUint64(quadro, uint64(n))
+	}
+	_, err := ws.conn.Write(append(quadro, dados...))
+	return err
+}
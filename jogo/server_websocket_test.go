@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// quadroTeste monta um quadro mascarado, como um cliente o enviaria.
+func quadroTeste(fin bool, opcode byte, dados string) []byte {
+	primeiro := opcode
+	if fin {
+		primeiro |= 0x80
+	}
+	mascara := [4]byte{0x12, 0x34, 0x56, 0x78}
+	quadro := append([]byte{primeiro, 0x80 | byte(len(dados))}, mascara[:]...)
+	for i := range len(dados) {
+		quadro = append(quadro, dados[i]^mascara[i%4])
+	}
+	return quadro
+}
+
+func TestWebSocketMensagemFragmentada(t *testing.T) {
+	servidor, cliente := net.Pipe()
+	defer cliente.Close()
+	go io.Copy(io.Discard, cliente) // Descarta o pong
+
+	quadros := bytes.Join([][]byte{
+		quadroTeste(false, wsTexto, `{"Acao":`),
+		quadroTeste(true, wsPing, "ping"), // Quadros de controle podem vir entre os fragmentos
+		quadroTeste(false, wsContinuacao, `"move",`),
+		quadroTeste(true, wsContinuacao, `"SequenceNumber":2}`),
+	}, nil)
+	ws := &conexaoWebSocket{conn: servidor, leitor: bufio.NewReader(bytes.NewReader(quadros))}
+
+	buf := make([]byte, 256)
+	n, err := ws.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got, want := string(buf[:n]), `{"Acao":"move","SequenceNumber":2}`; got != want {
+		t.Errorf("Read = %q, esperado %q numa única leitura", got, want)
+	}
+	if _, err := ws.Read(buf); err != io.EOF {
+		t.Errorf("Read após a mensagem = %v, esperado io.EOF", err)
+	}
+}
+
+func TestWebSocketFragmentacaoInvalida(t *testing.T) {
+	casos := []struct {
+		nome    string
+		quadros [][]byte
+	}{
+		{"continuação sem mensagem", [][]byte{quadroTeste(true, wsContinuacao, "x")}},
+		{"continuação após o FIN", [][]byte{quadroTeste(true, wsTexto, "a"), quadroTeste(true, wsContinuacao, "b")}},
+		{"nova mensagem antes do FIN", [][]byte{quadroTeste(false, wsTexto, "a"), quadroTeste(true, wsTexto, "b")}},
+		{"controle fragmentado", [][]byte{quadroTeste(false, wsPing, "p")}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			ws := &conexaoWebSocket{leitor: bufio.NewReader(bytes.NewReader(bytes.Join(c.quadros, nil)))}
+			buf := make([]byte, 256)
+			var err error
+			for err == nil {
+				_, err = ws.Read(buf)
+			}
+			if !errors.Is(err, errQuadroInvalido) {
+				t.Errorf("Read = %v, esperado %v", err, errQuadroInvalido)
+			}
+		})
+	}
+}